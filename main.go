@@ -17,8 +17,13 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -32,11 +37,20 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	kuadrantiov1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/config"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/dnshealth"
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/dnsrecord"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/domainverification"
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/secret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/secretsync"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/traffic"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/trafficpolicy"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/features"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/health"
 	//+kubebuilder:scaffold:imports
 
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/multiClusterWatch"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/tracing"
 )
 
 var (
@@ -52,12 +66,82 @@ func init() {
 }
 
 func main() {
-	var metricsAddr string
-	var enableLeaderElection bool
-	var probeAddr string
-	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
-	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
-	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
+	configFile := config.FileFromArgs(os.Args[1:])
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// the flags default to the configuration and override it when set
+	flag.String("config", configFile, "The path of the ControllerConfiguration file the flags override.")
+	flag.StringVar(&cfg.Metrics.BindAddress, "metrics-bind-address", cfg.Metrics.BindAddress, "The address the metric endpoint binds to.")
+	flag.StringVar(&cfg.Health.BindAddress, "health-probe-bind-address", cfg.Health.BindAddress, "The address the probe endpoint binds to.")
+	flag.DurationVar(&cfg.Health.ProviderCheckInterval.Duration, "provider-check-interval", cfg.Health.ProviderCheckInterval.Duration,
+		"How often the DNS provider is checked for the readiness check.")
+	flag.Float64Var(&cfg.Health.MinSyncedClusters, "min-synced-clusters", cfg.Health.MinSyncedClusters,
+		"The fraction of the watched workload clusters that must have synced for the controller to be ready, between 0 and 1. "+
+			"The clusters aren't checked when zero.")
+	flag.DurationVar(&cfg.Health.StuckWatcherTimeout.Duration, "stuck-watcher-timeout", cfg.Health.StuckWatcherTimeout.Duration,
+		"How long a cluster watcher may handle an object before the controller is no longer live.")
+	flag.DurationVar(&cfg.SyncPeriod.Duration, "sync-period", cfg.SyncPeriod.Duration,
+		"How often the objects of the control cluster are reconciled again when they don't change.")
+	flag.StringVar(&cfg.DNS.Provider, "dns-provider", cfg.DNS.Provider, "The DNS provider records are published with, aws or fake.")
+	flag.StringVar(&cfg.DNS.OwnerID, "owner-id", cfg.DNS.OwnerID,
+		"The owner ID of the DNSRecords, domain verifications and certificates managed by the controller. Every object is managed when empty.")
+	flag.StringVar(&cfg.Traffic.RecordNamespace, "dns-record-namespace", cfg.Traffic.RecordNamespace,
+		"The namespace DNSRecords, domain verifications and certificates for the traffic observed on the workload clusters are created in.")
+	flag.StringVar(&cfg.Traffic.ManagedZone, "managed-zone", cfg.Traffic.ManagedZone,
+		"The domain of the DNS zone hosts are generated under for managed traffic.")
+	flag.StringVar(&cfg.Traffic.ClusterIssuer, "cluster-issuer", cfg.Traffic.ClusterIssuer,
+		"The cert-manager cluster issuer certificates for the published hosts are requested from. TLS is not managed when empty.")
+	flag.DurationVar(&cfg.DNS.ProviderTimeout.Duration, "dns-provider-timeout", cfg.DNS.ProviderTimeout.Duration,
+		"The deadline of each call to the DNS provider.")
+	flag.Var(zonesValue{&cfg.DNS.Zones}, "dns-zones",
+		"The DNS zones records are published to, comma separated. A zone is its ID, followed by =namespace/name "+
+			"of the secret holding the credentials of its account when it isn't managed with the credentials of the environment. "+
+			"The zone of the AWS_DNS_PUBLIC_ZONE_ID environment variable is used when empty.")
+	flag.DurationVar(&cfg.HealthCheck.Interval.Duration, "health-check-interval", cfg.HealthCheck.Interval.Duration,
+		"The interval the endpoints of the published hosts are health checked at. Health checks are disabled when zero.")
+	flag.StringVar(&cfg.HealthCheck.Path, "health-check-path", cfg.HealthCheck.Path, "The path requested by the health checks.")
+	flag.StringVar(&cfg.HealthCheck.Protocol, "health-check-protocol", cfg.HealthCheck.Protocol, "The protocol of the health checks, HTTP or HTTPS.")
+	flag.IntVar(&cfg.HealthCheck.Port, "health-check-port", cfg.HealthCheck.Port,
+		"The port the health checks are sent to. The default port of the protocol is used when zero.")
+	flag.IntVar(&cfg.HealthCheck.ExpectedStatus, "health-check-expected-status", cfg.HealthCheck.ExpectedStatus,
+		"The status code of a healthy response.")
+	flag.IntVar(&cfg.HealthCheck.FailureThreshold, "health-check-failure-threshold", cfg.HealthCheck.FailureThreshold,
+		"The number of consecutive failed health checks after which an endpoint is removed from DNS.")
+	flag.Var(listValue{&cfg.ClusterSources.Sources}, "cluster-sources",
+		"The sources workload clusters are registered through, comma separated: argo, kubeconfig or ocm.")
+	flag.StringVar(&cfg.ClusterSources.Argo.Selector, "argo-cluster-selector", cfg.ClusterSources.Argo.Selector,
+		"The label selector of the Argo CD cluster secrets.")
+	flag.StringVar(&cfg.ClusterSources.Kubeconfig.Selector, "kubeconfig-cluster-selector", cfg.ClusterSources.Kubeconfig.Selector,
+		"The label selector of the secrets holding the kubeconfig of a workload cluster.")
+	flag.StringVar(&cfg.ClusterSources.OCM.Selector, "ocm-cluster-selector", cfg.ClusterSources.OCM.Selector,
+		"The label selector of the Open Cluster Management managed clusters. Every managed cluster is selected when empty.")
+	flag.StringVar(&cfg.ClusterSources.OCM.CredentialsSecret, "ocm-credentials-secret", cfg.ClusterSources.OCM.CredentialsSecret,
+		"The name of the secret in the namespace of a managed cluster holding the token and CA of the cluster.")
+	flag.Var(listValue{&cfg.Watch.Namespaces}, "watch-namespaces",
+		"The namespaces traffic is watched in on the workload clusters, comma separated. Every namespace is watched when empty.")
+	flag.Var(listValue{&cfg.Watch.ExcludedNamespaces}, "watch-excluded-namespaces",
+		"The namespaces traffic is not watched in on the workload clusters, comma separated.")
+	flag.StringVar(&cfg.Watch.LabelSelector, "watch-label-selector", cfg.Watch.LabelSelector,
+		"The label selector of the traffic watched on the workload clusters.")
+	flag.DurationVar(&cfg.Watch.ResyncPeriod.Duration, "watch-resync-period", cfg.Watch.ResyncPeriod.Duration,
+		"How often the traffic of the workload clusters is handled again when it doesn't change.")
+	flag.DurationVar(&cfg.SecretSync.ResyncPeriod.Duration, "secret-sync-resync-period", cfg.SecretSync.ResyncPeriod.Duration,
+		"How often the copies of the TLS secrets on the workload clusters are checked when the source doesn't change.")
+	flag.IntVar(&cfg.Concurrency.DNSRecord, "dns-record-concurrency", cfg.Concurrency.DNSRecord,
+		"The number of DNSRecords published concurrently.")
+	flag.StringVar(&cfg.Tracing.Endpoint, "otlp-endpoint", cfg.Tracing.Endpoint,
+		"The address of the OTLP gRPC collector traces are exported to. Tracing is disabled when empty.")
+	flag.BoolVar(&cfg.Tracing.Insecure, "otlp-insecure", cfg.Tracing.Insecure, "Connect to the OTLP collector without TLS.")
+	flag.Float64Var(cfg.Tracing.SampleRatio, "trace-sample-ratio", *cfg.Tracing.SampleRatio,
+		"The fraction of the traces started by the controller that are sampled, between 0 and 1.")
+	flag.Var(featureGatesValue{&cfg.FeatureGates}, "feature-gates",
+		"The features enabled or disabled, as comma separated name=true|false pairs overriding the configuration. "+
+			"Known features: "+strings.Join(featureNames(), ", ")+".")
+	flag.BoolVar(&cfg.LeaderElection.LeaderElect, "leader-elect", cfg.LeaderElection.LeaderElect,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
 	opts := zap.Options{
@@ -68,51 +152,295 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	if err := cfg.Validate(); err != nil {
+		setupLog.Error(err, "invalid configuration", "file", configFile)
+		os.Exit(1)
+	}
+	setupLog.Info("loaded configuration", "file", configFile, "configuration", cfg)
+	gates, err := features.New(cfg.FeatureGates)
+	if err != nil {
+		setupLog.Error(err, "invalid feature gates")
+		os.Exit(1)
+	}
+	gates.Report(setupLog)
+
+	ctx := ctrl.SetupSignalHandler()
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: *cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			setupLog.Error(err, "problem shutting down tracing")
+		}
+	}()
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
-		MetricsBindAddress:     metricsAddr,
+		MetricsBindAddress:     cfg.Metrics.BindAddress,
 		Port:                   9443,
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "fb80029c.kuadrant.io",
+		HealthProbeBindAddress: cfg.Health.BindAddress,
+		LeaderElection:         cfg.LeaderElection.LeaderElect,
+		LeaderElectionID:       cfg.LeaderElection.ResourceName,
+		SyncPeriod:             &cfg.SyncPeriod.Duration,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
-	if err = (&dnsrecord.DNSRecordReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+	dnsRecordReconciler := &dnsrecord.DNSRecordReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("dnsrecord"),
 		ReconcilerConfig: dnsrecord.DNSRecordReconcilerConfig{
-			DNSProvider: "aws",
+			DNSProvider:     cfg.DNS.Provider,
+			ProviderTimeout: cfg.DNS.ProviderTimeout.Duration,
+			Zones:           cfg.DNS.Zones,
+			OwnerID:         cfg.DNS.OwnerID,
 		},
-	}).SetupWithManager(mgr); err != nil {
+		MaxConcurrentReconciles: cfg.Concurrency.DNSRecord,
+	}
+	if err = dnsRecordReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "DNSRecord")
 		os.Exit(1)
 	}
-	if err = (&secret.SecretReconciler{
-		Client:  mgr.GetClient(),
-		Scheme:  mgr.GetScheme(),
-		MCWatch: &multiClusterWatch.WatchController{Manager: mgr, HandlerFactory: multiClusterWatch.NewTrafficHandlerFactory()},
+	if gates.Enabled(features.DNSHealthCheck) && cfg.HealthCheck.Interval.Duration > 0 {
+		if err = (&dnshealth.DNSHealthCheckReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+			Probe: dnshealth.ProbeConfig{
+				Path:           cfg.HealthCheck.Path,
+				Protocol:       cfg.HealthCheck.Protocol,
+				Port:           cfg.HealthCheck.Port,
+				ExpectedStatus: cfg.HealthCheck.ExpectedStatus,
+			},
+			Interval:                cfg.HealthCheck.Interval.Duration,
+			FailureThreshold:        cfg.HealthCheck.FailureThreshold,
+			OwnerID:                 cfg.DNS.OwnerID,
+			MaxConcurrentReconciles: cfg.Concurrency.DNSHealthCheck,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "DNSHealthCheck")
+			os.Exit(1)
+		}
+	}
+	if err = (&domainverification.DomainVerificationReconciler{
+		Client:                  mgr.GetClient(),
+		Scheme:                  mgr.GetScheme(),
+		OwnerID:                 cfg.DNS.OwnerID,
+		MaxConcurrentReconciles: cfg.Concurrency.DomainVerification,
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Secret")
+		setupLog.Error(err, "unable to create controller", "controller", "DomainVerification")
 		os.Exit(1)
 	}
+	var watchController *multiClusterWatch.WatchController
+	if gates.Enabled(features.MultiClusterWatch) {
+		trafficHandlerFactory := multiClusterWatch.NewTrafficHandlerFactory(traffic.ReconcilerConfig{
+			Namespace:     cfg.Traffic.RecordNamespace,
+			ManagedZone:   cfg.Traffic.ManagedZone,
+			ClusterIssuer: cfg.Traffic.ClusterIssuer,
+			OwnerID:       cfg.DNS.OwnerID,
+		})
+		watchController = &multiClusterWatch.WatchController{
+			Manager:        mgr,
+			HandlerFactory: trafficHandlerFactory,
+			Scope: multiClusterWatch.Scope{
+				Namespaces:         cfg.Watch.Namespaces,
+				ExcludedNamespaces: cfg.Watch.ExcludedNamespaces,
+				LabelSelector:      cfg.Watch.LabelSelector,
+			},
+			ResyncPeriod: cfg.Watch.ResyncPeriod.Duration,
+		}
+		for _, name := range cfg.ClusterSources.Sources {
+			var source secret.ClusterSource
+			switch name {
+			case "argo":
+				selector, err := secret.ParseSelector(name, cfg.ClusterSources.Argo.Selector)
+				if err != nil {
+					setupLog.Error(err, "invalid cluster source")
+					os.Exit(1)
+				}
+				source = &secret.ArgoSource{LabelSelector: selector}
+			case "kubeconfig":
+				selector, err := secret.ParseSelector(name, cfg.ClusterSources.Kubeconfig.Selector)
+				if err != nil {
+					setupLog.Error(err, "invalid cluster source")
+					os.Exit(1)
+				}
+				source = &secret.KubeconfigSource{LabelSelector: selector}
+			case "ocm":
+				selector, err := secret.ParseSelector(name, cfg.ClusterSources.OCM.Selector)
+				if err != nil {
+					setupLog.Error(err, "invalid cluster source")
+					os.Exit(1)
+				}
+				source = &secret.OCMSource{LabelSelector: selector, CredentialsSecret: cfg.ClusterSources.OCM.CredentialsSecret}
+			}
+			if err = (&secret.SecretReconciler{
+				Client:                  mgr.GetClient(),
+				Scheme:                  mgr.GetScheme(),
+				Recorder:                mgr.GetEventRecorderFor(name + "-cluster-source"),
+				MCWatch:                 watchController,
+				Source:                  source,
+				MaxConcurrentReconciles: cfg.Concurrency.ClusterSource,
+			}).SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create controller", "controller", "Secret", "source", name)
+				os.Exit(1)
+			}
+		}
+		if gates.Enabled(features.SecretSync) {
+			if err = (&secretsync.SecretSyncReconciler{
+				Client:                  mgr.GetClient(),
+				Scheme:                  mgr.GetScheme(),
+				Recorder:                mgr.GetEventRecorderFor("secret-sync"),
+				Clusters:                watchController,
+				Namespace:               cfg.Traffic.RecordNamespace,
+				OwnerID:                 cfg.DNS.OwnerID,
+				ResyncPeriod:            cfg.SecretSync.ResyncPeriod.Duration,
+				MaxConcurrentReconciles: cfg.Concurrency.SecretSync,
+			}).SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create controller", "controller", "SecretSync")
+				os.Exit(1)
+			}
+		}
+		if err = (&trafficpolicy.TrafficPolicyReconciler{
+			Client:    mgr.GetClient(),
+			Scheme:    mgr.GetScheme(),
+			Traffic:   watchController,
+			Namespace: cfg.Traffic.RecordNamespace,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "TrafficPolicy")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
-	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
-		setupLog.Error(err, "unable to set up health check")
-		os.Exit(1)
+	healthChecks := map[string]healthz.Checker{
+		"ping": healthz.Ping,
+	}
+	readyChecks := map[string]healthz.Checker{
+		"ping":       healthz.Ping,
+		"cache-sync": health.CacheSynced(mgr.GetCache(), time.Second),
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
-		setupLog.Error(err, "unable to set up ready check")
+	providerCheck := &health.Background{Check: dnsRecordReconciler.CheckProviders, Interval: cfg.Health.ProviderCheckInterval.Duration}
+	if err := mgr.Add(providerCheck); err != nil {
+		setupLog.Error(err, "unable to set up ready check", "check", "dns-provider")
 		os.Exit(1)
 	}
+	readyChecks["dns-provider"] = providerCheck.Checker
+	if watchController != nil {
+		healthChecks["cluster-watchers"] = watchController.CheckWatchers(cfg.Health.StuckWatcherTimeout.Duration)
+		if cfg.Health.MinSyncedClusters > 0 {
+			readyChecks["workload-clusters"] = watchController.CheckSynced(cfg.Health.MinSyncedClusters)
+		}
+	}
+	for name, check := range healthChecks {
+		if err := mgr.AddHealthzCheck(name, check); err != nil {
+			setupLog.Error(err, "unable to set up health check", "check", name)
+			os.Exit(1)
+		}
+	}
+	for name, check := range readyChecks {
+		if err := mgr.AddReadyzCheck(name, check); err != nil {
+			setupLog.Error(err, "unable to set up ready check", "check", name)
+			os.Exit(1)
+		}
+	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+// listValue is a flag setting a comma separated list.
+type listValue struct {
+	list *[]string
+}
+
+func (v listValue) String() string {
+	if v.list == nil {
+		return ""
+	}
+	return strings.Join(*v.list, ",")
+}
+
+func (v listValue) Set(value string) error {
+	*v.list = multiClusterWatch.SplitNamespaces(value)
+	return nil
+}
+
+// featureGatesValue is a flag setting the state of features, on top of the
+// feature gates of the configuration.
+type featureGatesValue struct {
+	gates *map[string]bool
+}
+
+func (v featureGatesValue) String() string {
+	if v.gates == nil {
+		return ""
+	}
+	var pairs []string
+	for name, enabled := range *v.gates {
+		pairs = append(pairs, fmt.Sprintf("%s=%t", name, enabled))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (v featureGatesValue) Set(value string) error {
+	gates, err := features.Parse(value)
+	if err != nil {
+		return err
+	}
+	if *v.gates == nil {
+		*v.gates = map[string]bool{}
+	}
+	for name, enabled := range gates {
+		(*v.gates)[name] = enabled
+	}
+	return nil
+}
+
+func featureNames() []string {
+	var names []string
+	for _, feature := range features.All() {
+		names = append(names, string(feature))
+	}
+	return names
+}
+
+// zonesValue is a flag setting the DNS zones.
+type zonesValue struct {
+	zones *[]kuadrantiov1.DNSZone
+}
+
+func (v zonesValue) String() string {
+	if v.zones == nil {
+		return ""
+	}
+	var zones []string
+	for _, zone := range *v.zones {
+		if ref := zone.CredentialsSecretRef; ref != nil {
+			zones = append(zones, zone.ID+"="+ref.Namespace+"/"+ref.Name)
+		} else {
+			zones = append(zones, zone.ID)
+		}
+	}
+	return strings.Join(zones, ",")
+}
+
+func (v zonesValue) Set(value string) error {
+	zones, err := dnsrecord.ParseZones(value)
+	if err != nil {
+		return err
+	}
+	*v.zones = zones
+	return nil
+}
@@ -89,6 +89,18 @@ func (e *Endpoint) WithProviderSpecific(key, value string) *Endpoint {
 	return e
 }
 
+// SetProviderSpecificProperty sets the value of a ProviderSpecificProperty,
+// adding the property if it does not exist.
+func (e *Endpoint) SetProviderSpecificProperty(key, value string) {
+	for i := range e.ProviderSpecific {
+		if e.ProviderSpecific[i].Name == key {
+			e.ProviderSpecific[i].Value = value
+			return
+		}
+	}
+	e.WithProviderSpecific(key, value)
+}
+
 // GetProviderSpecificProperty returns a ProviderSpecificProperty if the property exists.
 func (e *Endpoint) GetProviderSpecificProperty(key string) (ProviderSpecificProperty, bool) {
 	for _, providerSpecific := range e.ProviderSpecific {
@@ -123,6 +135,10 @@ type DNSRecordStatus struct {
 	// needs to retry the update for that specific zone.
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// healthChecks are the results of probing the targets of each endpoint.
+	// +optional
+	HealthChecks []HealthCheckStatus `json:"healthChecks,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -180,6 +196,20 @@ type DNSZone struct {
 	// [1]: https://docs.aws.amazon.com/cli/latest/reference/resourcegroupstaggingapi/get-resources.html#options
 	// +optional
 	Tags map[string]string `json:"tags,omitempty"`
+
+	// credentialsSecretRef references the secret holding the credentials of
+	// the DNS provider account the zone is managed in. The credentials of the
+	// controller's environment are used when it is not set.
+	// +optional
+	CredentialsSecretRef *SecretReference `json:"credentialsSecretRef,omitempty"`
+}
+
+// SecretReference references a secret by namespace and name.
+type SecretReference struct {
+	// name of the secret.
+	Name string `json:"name"`
+	// namespace of the secret.
+	Namespace string `json:"namespace"`
 }
 
 // DNSZoneStatus is the status of a record within a specific zone.
@@ -221,6 +251,23 @@ type DNSZoneCondition struct {
 	Message            string      `json:"message,omitempty"`
 }
 
+// HealthCheckStatus is the result of probing the targets of an endpoint.
+type HealthCheckStatus struct {
+	// setIdentifier of the endpoint that was probed.
+	SetIdentifier string `json:"setIdentifier"`
+	// healthy is false once the endpoint failed as many consecutive probes as
+	// the failure threshold, and true again after it passes a probe.
+	Healthy bool `json:"healthy"`
+	// consecutiveFailures is the number of probes failed in a row.
+	// +optional
+	ConsecutiveFailures int `json:"consecutiveFailures,omitempty"`
+	// lastChecked is the time of the last probe that changed the check.
+	LastChecked metav1.Time `json:"lastChecked,omitempty"`
+	// message describes the outcome of the last probe.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
 func init() {
 	SchemeBuilder.Register(&DNSRecord{}, &DNSRecordList{})
 }
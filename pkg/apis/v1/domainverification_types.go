@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DomainVerificationRecordPrefix is prepended to a domain to get the name of
+// the TXT record the verification token is expected in
+const DomainVerificationRecordPrefix = "_mctc-verify."
+
+// DomainVerificationSpec defines the desired state of DomainVerification
+type DomainVerificationSpec struct {
+	// domain is the host being verified
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Domain string `json:"domain"`
+
+	// owner identifies the workload cluster and traffic object that requested
+	// the domain. A domain verified for one owner is not verified for any
+	// other owner.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Owner string `json:"owner"`
+
+	// token is the value expected in the TXT record for the domain
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Token string `json:"token"`
+}
+
+// DomainVerificationStatus defines the observed state of DomainVerification
+type DomainVerificationStatus struct {
+	// verified is true once the token was found in the TXT record
+	// +optional
+	Verified bool `json:"verified,omitempty"`
+
+	// lastChecked is the last time the TXT record was looked up
+	// +optional
+	LastChecked *metav1.Time `json:"lastChecked,omitempty"`
+
+	// message describes the outcome of the last check
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Domain",type="string",JSONPath=".spec.domain"
+//+kubebuilder:printcolumn:name="Verified",type="boolean",JSONPath=".status.verified"
+
+// DomainVerification is the Schema for the domainverifications API
+type DomainVerification struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DomainVerificationSpec   `json:"spec,omitempty"`
+	Status DomainVerificationStatus `json:"status,omitempty"`
+}
+
+// RecordName returns the name of the TXT record the token is expected in
+func (v *DomainVerification) RecordName() string {
+	return DomainVerificationRecordPrefix + v.Spec.Domain
+}
+
+//+kubebuilder:object:root=true
+
+// DomainVerificationList contains a list of DomainVerification
+type DomainVerificationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DomainVerification `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DomainVerification{}, &DomainVerificationList{})
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TrafficTargetReference identifies a traffic object on the workload clusters
+type TrafficTargetReference struct {
+	// kind of the traffic object, e.g. Ingress
+	// +kubebuilder:validation:Required
+	Kind string `json:"kind"`
+	// namespace of the traffic object on the workload clusters
+	// +kubebuilder:validation:Required
+	Namespace string `json:"namespace"`
+	// name of the traffic object
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+}
+
+// ClusterSelector selects workload clusters by name or by label. A cluster is
+// selected when it is listed in clusters or matches the selector.
+type ClusterSelector struct {
+	// clusters are the names of the selected clusters
+	// +optional
+	Clusters []string `json:"clusters,omitempty"`
+	// selector matches the labels of the selected clusters
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// ClusterWeight is the share of the traffic sent to the selected clusters
+type ClusterWeight struct {
+	ClusterSelector `json:",inline"`
+	// weight of each selected cluster relative to the other clusters serving
+	// the host
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=255
+	Weight int `json:"weight"`
+}
+
+// GeoMapping sends the clients in a continent to the selected clusters
+type GeoMapping struct {
+	ClusterSelector `json:",inline"`
+	// geo is the continent code of the clients
+	// +kubebuilder:validation:Enum=AF;AN;AS;EU;NA;OC;SA
+	Geo string `json:"geo"`
+}
+
+// GeoPolicy routes clients to the clusters of their continent
+type GeoPolicy struct {
+	// defaultGeo is the geo whose clusters serve the clients of the continents
+	// that are not mapped
+	// +kubebuilder:validation:Enum=AF;AN;AS;EU;NA;OC;SA
+	DefaultGeo string `json:"defaultGeo"`
+	// mappings assign clusters to geos, the first mapping selecting a cluster
+	// wins and clusters no mapping selects are in the default geo
+	// +kubebuilder:validation:MinItems=1
+	Mappings []GeoMapping `json:"mappings"`
+}
+
+// TrafficPolicySpec defines the desired state of TrafficPolicy
+type TrafficPolicySpec struct {
+	// hosts the policy applies to
+	// +optional
+	Hosts []string `json:"hosts,omitempty"`
+	// targetRefs are the traffic objects the policy applies to the hosts of
+	// +optional
+	TargetRefs []TrafficTargetReference `json:"targetRefs,omitempty"`
+	// weights assign the share of the traffic of each cluster, the first
+	// weight selecting a cluster wins and clusters no weight selects get the
+	// default weight
+	// +optional
+	Weights []ClusterWeight `json:"weights,omitempty"`
+	// geo routes clients to the clusters of their continent
+	// +optional
+	Geo *GeoPolicy `json:"geo,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// TrafficPolicy is the Schema for the trafficpolicies API. The policy is
+// compiled into the endpoints of the DNSRecords of the hosts it applies to,
+// the traffic serving them is reconciled again when the policy changes. When
+// several policies apply to a host the oldest one is used.
+type TrafficPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec TrafficPolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// TrafficPolicyList contains a list of TrafficPolicy
+type TrafficPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TrafficPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TrafficPolicy{}, &TrafficPolicyList{})
+}
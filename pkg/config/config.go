@@ -0,0 +1,405 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/dnshealth"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/secret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/secretsync"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/features"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/multiClusterWatch"
+)
+
+const (
+	// APIVersion and Kind identify the version of a configuration file
+	APIVersion = "config.kuadrant.io/v1alpha1"
+	Kind       = "ControllerConfiguration"
+
+	// The DNS providers records are published with, the fake provider
+	// publishes nothing
+	AWSProvider  = "aws"
+	FakeProvider = "fake"
+)
+
+var (
+	providers      = []string{AWSProvider, FakeProvider}
+	clusterSources = []string{"argo", "kubeconfig", "ocm"}
+)
+
+// ControllerConfiguration configures the controller manager. It is loaded
+// from a file and overridden by the flags of the manager.
+type ControllerConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Metrics        MetricsConfiguration        `json:"metrics,omitempty"`
+	Health         HealthConfiguration         `json:"health,omitempty"`
+	LeaderElection LeaderElectionConfiguration `json:"leaderElection,omitempty"`
+	// SyncPeriod is how often the objects of the control cluster are
+	// reconciled again when they don't change
+	SyncPeriod metav1.Duration `json:"syncPeriod,omitempty"`
+
+	DNS            DNSConfiguration            `json:"dns,omitempty"`
+	Traffic        TrafficConfiguration        `json:"traffic,omitempty"`
+	HealthCheck    HealthCheckConfiguration    `json:"healthCheck,omitempty"`
+	ClusterSources ClusterSourcesConfiguration `json:"clusterSources,omitempty"`
+	Watch          WatchConfiguration          `json:"watch,omitempty"`
+	SecretSync     SecretSyncConfiguration     `json:"secretSync,omitempty"`
+	Concurrency    ConcurrencyConfiguration    `json:"concurrency,omitempty"`
+	Tracing        TracingConfiguration        `json:"tracing,omitempty"`
+
+	// FeatureGates enables or disables features by name, the features
+	// that aren't set have their default state
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+}
+
+type MetricsConfiguration struct {
+	// BindAddress is the address the metric endpoint binds to
+	BindAddress string `json:"bindAddress,omitempty"`
+}
+
+type HealthConfiguration struct {
+	// BindAddress is the address the probe endpoint binds to
+	BindAddress string `json:"bindAddress,omitempty"`
+	// ProviderCheckInterval is how often the DNS provider is checked in the
+	// background, the readiness check reports the last result
+	ProviderCheckInterval metav1.Duration `json:"providerCheckInterval,omitempty"`
+	// MinSyncedClusters is the fraction of the watched workload clusters
+	// that must have synced for the controller to be ready, between 0 and 1.
+	// The clusters aren't checked when zero.
+	MinSyncedClusters float64 `json:"minSyncedClusters,omitempty"`
+	// StuckWatcherTimeout is how long a cluster watcher may handle an object
+	// before the controller is no longer live
+	StuckWatcherTimeout metav1.Duration `json:"stuckWatcherTimeout,omitempty"`
+}
+
+type LeaderElectionConfiguration struct {
+	// LeaderElect ensures there is only one active controller manager
+	LeaderElect bool `json:"leaderElect,omitempty"`
+	// ResourceName is the name of the lease of the leader
+	ResourceName string `json:"resourceName,omitempty"`
+}
+
+type DNSConfiguration struct {
+	// Provider is the DNS provider records are published with
+	Provider string `json:"provider,omitempty"`
+	// ProviderTimeout is the deadline of each call to the provider
+	ProviderTimeout metav1.Duration `json:"providerTimeout,omitempty"`
+	// Zones are the zones records are published to. The zone of the
+	// AWS_DNS_PUBLIC_ZONE_ID environment variable is used when empty.
+	Zones []v1.DNSZone `json:"zones,omitempty"`
+	// OwnerID identifies the records, domain verifications and certificates
+	// managed by the controller, so controllers with different owner IDs can
+	// share a control cluster
+	OwnerID string `json:"ownerID,omitempty"`
+}
+
+type TrafficConfiguration struct {
+	// RecordNamespace is the namespace DNSRecords, domain verifications and
+	// certificates for the traffic of the workload clusters are created in
+	RecordNamespace string `json:"recordNamespace,omitempty"`
+	// ManagedZone is the domain hosts are generated under for managed traffic
+	ManagedZone string `json:"managedZone,omitempty"`
+	// ClusterIssuer is the cert-manager cluster issuer certificates are
+	// requested from, TLS is not managed when empty
+	ClusterIssuer string `json:"clusterIssuer,omitempty"`
+}
+
+type HealthCheckConfiguration struct {
+	// Interval the endpoints are health checked at, health checks are
+	// disabled when zero
+	Interval         metav1.Duration `json:"interval,omitempty"`
+	Path             string          `json:"path,omitempty"`
+	Protocol         string          `json:"protocol,omitempty"`
+	Port             int             `json:"port,omitempty"`
+	ExpectedStatus   int             `json:"expectedStatus,omitempty"`
+	FailureThreshold int             `json:"failureThreshold,omitempty"`
+}
+
+type ClusterSourcesConfiguration struct {
+	// Sources are the sources workload clusters are registered through
+	Sources    []string                      `json:"sources,omitempty"`
+	Argo       ClusterSourceConfiguration    `json:"argo,omitempty"`
+	Kubeconfig ClusterSourceConfiguration    `json:"kubeconfig,omitempty"`
+	OCM        OCMClusterSourceConfiguration `json:"ocm,omitempty"`
+}
+
+type ClusterSourceConfiguration struct {
+	// Selector is the label selector of the objects registering clusters
+	Selector string `json:"selector,omitempty"`
+}
+
+type OCMClusterSourceConfiguration struct {
+	// Selector is the label selector of the managed clusters, every managed
+	// cluster is selected when empty
+	Selector string `json:"selector,omitempty"`
+	// CredentialsSecret is the name of the secret in the namespace of a
+	// managed cluster holding the token and CA of the cluster
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+}
+
+type WatchConfiguration struct {
+	// Namespaces, ExcludedNamespaces and LabelSelector scope the traffic
+	// watched on the workload clusters
+	Namespaces         []string `json:"namespaces,omitempty"`
+	ExcludedNamespaces []string `json:"excludedNamespaces,omitempty"`
+	LabelSelector      string   `json:"labelSelector,omitempty"`
+	// ResyncPeriod is how often the traffic of the workload clusters is
+	// handled again when it doesn't change
+	ResyncPeriod metav1.Duration `json:"resyncPeriod,omitempty"`
+}
+
+type SecretSyncConfiguration struct {
+	// ResyncPeriod is how often the copies of the TLS secrets are checked
+	// when the source doesn't change
+	ResyncPeriod metav1.Duration `json:"resyncPeriod,omitempty"`
+}
+
+// ConcurrencyConfiguration is the number of objects each controller
+// reconciles concurrently
+type ConcurrencyConfiguration struct {
+	DNSRecord          int `json:"dnsRecord,omitempty"`
+	DNSHealthCheck     int `json:"dnsHealthCheck,omitempty"`
+	DomainVerification int `json:"domainVerification,omitempty"`
+	ClusterSource      int `json:"clusterSource,omitempty"`
+	SecretSync         int `json:"secretSync,omitempty"`
+}
+
+type TracingConfiguration struct {
+	// Endpoint is the OTLP gRPC collector traces are exported to, tracing
+	// is disabled when empty
+	Endpoint string `json:"endpoint,omitempty"`
+	Insecure bool   `json:"insecure,omitempty"`
+	// SampleRatio is the fraction of the traces that are sampled, every
+	// trace is sampled when unset
+	SampleRatio *float64 `json:"sampleRatio,omitempty"`
+}
+
+// Load reads the configuration file and sets the defaults of the fields it
+// doesn't set. The default configuration is returned when the path is empty.
+func Load(path string) (*ControllerConfiguration, error) {
+	c := &ControllerConfiguration{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the configuration: %w", err)
+		}
+		if err := yaml.UnmarshalStrict(data, c); err != nil {
+			return nil, fmt.Errorf("failed to decode the configuration %s: %w", path, err)
+		}
+		if c.APIVersion != APIVersion || c.Kind != Kind {
+			return nil, fmt.Errorf("unsupported configuration %s, expected apiVersion %s and kind %s", c.GroupVersionKind(), APIVersion, Kind)
+		}
+	}
+	c.Default()
+	return c, nil
+}
+
+// Default sets the defaults of the unset fields.
+func (c *ControllerConfiguration) Default() {
+	c.APIVersion = APIVersion
+	c.Kind = Kind
+	setDefault(&c.Metrics.BindAddress, ":8080")
+	setDefault(&c.Health.BindAddress, ":8081")
+	setDefaultDuration(&c.Health.ProviderCheckInterval, time.Minute)
+	setDefaultDuration(&c.Health.StuckWatcherTimeout, 10*time.Minute)
+	setDefault(&c.LeaderElection.ResourceName, "fb80029c.kuadrant.io")
+	setDefaultDuration(&c.SyncPeriod, 10*time.Hour)
+
+	setDefault(&c.DNS.Provider, AWSProvider)
+	setDefaultDuration(&c.DNS.ProviderTimeout, dns.DefaultTimeout)
+	setDefault(&c.Traffic.RecordNamespace, "default")
+
+	setDefault(&c.HealthCheck.Path, dnshealth.DefaultPath)
+	setDefault(&c.HealthCheck.Protocol, "HTTP")
+	if c.HealthCheck.ExpectedStatus == 0 {
+		c.HealthCheck.ExpectedStatus = dnshealth.DefaultExpectedStatus
+	}
+	if c.HealthCheck.FailureThreshold == 0 {
+		c.HealthCheck.FailureThreshold = dnshealth.DefaultFailureThreshold
+	}
+
+	if len(c.ClusterSources.Sources) == 0 {
+		c.ClusterSources.Sources = []string{"argo"}
+	}
+	setDefault(&c.ClusterSources.Argo.Selector, secret.DefaultArgoSelector)
+	setDefault(&c.ClusterSources.Kubeconfig.Selector, secret.DefaultKubeconfigSelector)
+	setDefault(&c.ClusterSources.OCM.CredentialsSecret, secret.DefaultOCMCredentialsSecret)
+
+	setDefaultDuration(&c.Watch.ResyncPeriod, multiClusterWatch.RESYNC_PERIOD)
+	setDefaultDuration(&c.SecretSync.ResyncPeriod, secretsync.DefaultResyncPeriod)
+
+	for _, concurrency := range []*int{
+		&c.Concurrency.DNSRecord,
+		&c.Concurrency.DNSHealthCheck,
+		&c.Concurrency.DomainVerification,
+		&c.Concurrency.ClusterSource,
+		&c.Concurrency.SecretSync,
+	} {
+		if *concurrency == 0 {
+			*concurrency = 1
+		}
+	}
+	if c.Tracing.SampleRatio == nil {
+		sampleRatio := 1.0
+		c.Tracing.SampleRatio = &sampleRatio
+	}
+}
+
+// Validate returns an error listing the invalid fields.
+func (c *ControllerConfiguration) Validate() error {
+	var errs []string
+	invalid := func(field string, format string, args ...interface{}) {
+		errs = append(errs, field+": "+fmt.Sprintf(format, args...))
+	}
+
+	if c.Health.MinSyncedClusters < 0 || c.Health.MinSyncedClusters > 1 {
+		invalid("health.minSyncedClusters", "must be between 0 and 1")
+	}
+
+	if !slice.ContainsString(providers, c.DNS.Provider) {
+		invalid("dns.provider", "unsupported provider '%v', expected one of %v", c.DNS.Provider, providers)
+	}
+	if c.DNS.ProviderTimeout.Duration <= 0 {
+		invalid("dns.providerTimeout", "must be positive")
+	}
+	for i, zone := range c.DNS.Zones {
+		if zone.ID == "" {
+			invalid(fmt.Sprintf("dns.zones[%d].id", i), "is required")
+		}
+		if ref := zone.CredentialsSecretRef; ref != nil && (ref.Namespace == "" || ref.Name == "") {
+			invalid(fmt.Sprintf("dns.zones[%d].credentialsSecretRef", i), "namespace and name are required")
+		}
+	}
+	if c.Traffic.RecordNamespace == "" {
+		invalid("traffic.recordNamespace", "is required")
+	}
+
+	if c.HealthCheck.Interval.Duration < 0 {
+		invalid("healthCheck.interval", "must not be negative")
+	}
+	if c.FeatureGates[string(features.DNSHealthCheck)] && c.HealthCheck.Interval.Duration == 0 {
+		invalid("healthCheck.interval", "must be set when the %v feature gate is enabled", features.DNSHealthCheck)
+	}
+	if c.HealthCheck.Protocol != "HTTP" && c.HealthCheck.Protocol != "HTTPS" {
+		invalid("healthCheck.protocol", "unsupported protocol '%v', expected HTTP or HTTPS", c.HealthCheck.Protocol)
+	}
+	if c.HealthCheck.Port < 0 || c.HealthCheck.Port > 65535 {
+		invalid("healthCheck.port", "must be between 0 and 65535")
+	}
+	if c.HealthCheck.FailureThreshold < 1 {
+		invalid("healthCheck.failureThreshold", "must be at least 1")
+	}
+
+	for _, source := range c.ClusterSources.Sources {
+		if !slice.ContainsString(clusterSources, source) {
+			invalid("clusterSources.sources", "unknown cluster source '%v', expected one of %v", source, clusterSources)
+		}
+	}
+	for field, selector := range map[string]string{
+		"clusterSources.argo.selector":       c.ClusterSources.Argo.Selector,
+		"clusterSources.kubeconfig.selector": c.ClusterSources.Kubeconfig.Selector,
+		"clusterSources.ocm.selector":        c.ClusterSources.OCM.Selector,
+		"watch.labelSelector":                c.Watch.LabelSelector,
+	} {
+		if _, err := labels.Parse(selector); err != nil {
+			invalid(field, "%v", err)
+		}
+	}
+
+	for field, period := range map[string]metav1.Duration{
+		"syncPeriod":                   c.SyncPeriod,
+		"health.providerCheckInterval": c.Health.ProviderCheckInterval,
+		"health.stuckWatcherTimeout":   c.Health.StuckWatcherTimeout,
+		"watch.resyncPeriod":           c.Watch.ResyncPeriod,
+		"secretSync.resyncPeriod":      c.SecretSync.ResyncPeriod,
+	} {
+		if period.Duration <= 0 {
+			invalid(field, "must be positive")
+		}
+	}
+	for field, concurrency := range map[string]int{
+		"concurrency.dnsRecord":          c.Concurrency.DNSRecord,
+		"concurrency.dnsHealthCheck":     c.Concurrency.DNSHealthCheck,
+		"concurrency.domainVerification": c.Concurrency.DomainVerification,
+		"concurrency.clusterSource":      c.Concurrency.ClusterSource,
+		"concurrency.secretSync":         c.Concurrency.SecretSync,
+	} {
+		if concurrency < 1 {
+			invalid(field, "must be at least 1")
+		}
+	}
+	if ratio := c.Tracing.SampleRatio; ratio != nil && (*ratio < 0 || *ratio > 1) {
+		invalid("tracing.sampleRatio", "must be between 0 and 1")
+	}
+	for name := range c.FeatureGates {
+		if !features.Known(name) {
+			invalid("featureGates", "unknown feature gate '%v', expected one of %v", name, features.All())
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.Strings(errs)
+	return fmt.Errorf("invalid configuration: %s", strings.Join(errs, ", "))
+}
+
+// FileFromArgs returns the value of the --config flag in the arguments, so
+// the configuration can be loaded before the flags overriding it are parsed.
+func FileFromArgs(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		if len(arg)-len(name) == 0 || len(arg)-len(name) > 2 {
+			continue
+		}
+		if name == "config" && i+1 < len(args) {
+			return args[i+1]
+		}
+		if strings.HasPrefix(name, "config=") {
+			return strings.TrimPrefix(name, "config=")
+		}
+	}
+	return ""
+}
+
+func setDefault(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
+
+func setDefaultDuration(field *metav1.Duration, value time.Duration) {
+	if field.Duration == 0 {
+		field.Duration = value
+	}
+}
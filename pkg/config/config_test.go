@@ -0,0 +1,195 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		expectErr string
+		verify    func(t *testing.T, c *ControllerConfiguration)
+	}{
+		{
+			name: "file overrides the defaults",
+			content: `apiVersion: config.kuadrant.io/v1alpha1
+kind: ControllerConfiguration
+dns:
+  provider: fake
+  zones:
+  - id: zone-a
+  - id: zone-b
+    credentialsSecretRef:
+      namespace: test-namespace
+      name: test-secret
+watch:
+  resyncPeriod: 5m
+concurrency:
+  dnsRecord: 4
+`,
+			verify: func(t *testing.T, c *ControllerConfiguration) {
+				if c.DNS.Provider != FakeProvider {
+					t.Errorf("expected provider %v got %v", FakeProvider, c.DNS.Provider)
+				}
+				if len(c.DNS.Zones) != 2 || c.DNS.Zones[1].CredentialsSecretRef == nil || c.DNS.Zones[1].CredentialsSecretRef.Name != "test-secret" {
+					t.Errorf("expected zones with a secret reference got %+v", c.DNS.Zones)
+				}
+				if c.Watch.ResyncPeriod.Duration != 5*time.Minute {
+					t.Errorf("expected resync period 5m got %v", c.Watch.ResyncPeriod.Duration)
+				}
+				if c.Concurrency.DNSRecord != 4 || c.Concurrency.SecretSync != 1 {
+					t.Errorf("expected dnsRecord concurrency 4 and secretSync 1 got %+v", c.Concurrency)
+				}
+				if c.Metrics.BindAddress != ":8080" {
+					t.Errorf("expected default metrics address got %v", c.Metrics.BindAddress)
+				}
+			},
+		},
+		{
+			name:    "sample ratio of zero",
+			content: "apiVersion: config.kuadrant.io/v1alpha1\nkind: ControllerConfiguration\ntracing:\n  sampleRatio: 0\n",
+			verify: func(t *testing.T, c *ControllerConfiguration) {
+				if c.Tracing.SampleRatio == nil || *c.Tracing.SampleRatio != 0 {
+					t.Errorf("expected sample ratio 0 got %v", c.Tracing.SampleRatio)
+				}
+			},
+		},
+		{
+			name:      "unknown field",
+			content:   "apiVersion: config.kuadrant.io/v1alpha1\nkind: ControllerConfiguration\ndsn: {}\n",
+			expectErr: "unknown field",
+		},
+		{
+			name:      "unsupported version",
+			content:   "apiVersion: config.kuadrant.io/v1\nkind: ControllerConfiguration\n",
+			expectErr: "unsupported configuration",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			c, err := Load(path)
+			if tt.expectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectErr) {
+					t.Fatalf("expected error '%v' got '%v'", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tt.verify(t, c)
+		})
+	}
+}
+
+func TestLoadDefault(t *testing.T) {
+	c, err := Load("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Validate(); err != nil {
+		t.Errorf("expected the default configuration to be valid got '%v'", err)
+	}
+	if c.APIVersion != APIVersion || c.Kind != Kind {
+		t.Errorf("expected %v %v got %v %v", APIVersion, Kind, c.APIVersion, c.Kind)
+	}
+	if c.Tracing.SampleRatio == nil || *c.Tracing.SampleRatio != 1 {
+		t.Errorf("expected sample ratio 1 got %v", c.Tracing.SampleRatio)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		modify    func(c *ControllerConfiguration)
+		expectErr []string
+	}{
+		{
+			name:   "valid",
+			modify: func(c *ControllerConfiguration) {},
+		},
+		{
+			name: "health checks enabled without an interval",
+			modify: func(c *ControllerConfiguration) {
+				c.FeatureGates = map[string]bool{"DNSHealthCheck": true}
+			},
+			expectErr: []string{"healthCheck.interval"},
+		},
+		{
+			name: "invalid fields",
+			modify: func(c *ControllerConfiguration) {
+				c.DNS.Provider = "azure"
+				c.HealthCheck.Interval.Duration = -time.Minute
+				c.HealthCheck.Protocol = "TCP"
+				c.ClusterSources.Sources = []string{"argo", "rancher"}
+				c.Watch.LabelSelector = "a in (b"
+				c.Concurrency.DNSRecord = -1
+				sampleRatio := 2.0
+				c.Tracing.SampleRatio = &sampleRatio
+				c.FeatureGates = map[string]bool{"Unknown": true}
+			},
+			expectErr: []string{
+				"clusterSources.sources",
+				"concurrency.dnsRecord",
+				"dns.provider",
+				"featureGates",
+				"healthCheck.interval",
+				"healthCheck.protocol",
+				"tracing.sampleRatio",
+				"watch.labelSelector",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &ControllerConfiguration{}
+			c.Default()
+			tt.modify(c)
+			err := c.Validate()
+			if len(tt.expectErr) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected errors for %v", tt.expectErr)
+			}
+			for _, field := range tt.expectErr {
+				if !strings.Contains(err.Error(), field+": ") {
+					t.Errorf("expected error for %v got '%v'", field, err)
+				}
+			}
+		})
+	}
+}
+
+func TestFileFromArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		expect string
+	}{
+		{name: "no flag", args: []string{"--leader-elect"}},
+		{name: "separate value", args: []string{"--leader-elect", "--config", "a.yaml"}, expect: "a.yaml"},
+		{name: "single dash", args: []string{"-config=b.yaml"}, expect: "b.yaml"},
+		{name: "after terminator", args: []string{"--", "--config=c.yaml"}},
+		{name: "other flag", args: []string{"--config-dir=d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := FileFromArgs(tt.args); actual != tt.expect {
+				t.Errorf("expected '%v' got '%v'", tt.expect, actual)
+			}
+		})
+	}
+}
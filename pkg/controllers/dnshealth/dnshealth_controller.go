@@ -0,0 +1,305 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dnshealth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilclock "k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	trafficController "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/traffic"
+	dnsAWS "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns/aws"
+)
+
+// DNSHealthCheckReconciler probes the targets of the endpoints in the
+// DNSRecords generated from workload cluster traffic. Endpoints that fail
+// FailureThreshold consecutive probes are drained by publishing them with a
+// zero weight, and restored once a probe passes again. The probes run in the
+// background, the record is reconciled again with their outcome once they
+// complete.
+type DNSHealthCheckReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Probe  ProbeConfig
+	// Interval between the probes of a record, must be positive
+	Interval time.Duration
+	// FailureThreshold is the number of consecutive failed probes after
+	// which an endpoint is drained, DefaultFailureThreshold is used when zero
+	FailureThreshold int
+	// OwnerID restricts the records probed to those labelled with it, the
+	// records of other controllers sharing the control cluster are left
+	// alone
+	OwnerID string
+	// MaxConcurrentReconciles is the number of objects reconciled
+	// concurrently, one when zero
+	MaxConcurrentReconciles int
+
+	initOnce sync.Once
+	lock     sync.Mutex
+	// probing holds the records with probes in progress
+	probing map[types.NamespacedName]bool
+	// lastProbed holds when the probes of each record last started
+	lastProbed map[types.NamespacedName]time.Time
+	// results holds the outcome of the completed probes of each record until
+	// it is applied
+	results map[types.NamespacedName]*probeResult
+	// probed receives the records whose probes completed
+	probed chan event.GenericEvent
+}
+
+// probeResult is the outcome of probing the endpoints of a record, the error
+// of every probed endpoint keyed by its set identifier, nil when it passed
+type probeResult struct {
+	checked metav1.Time
+	errs    map[string]error
+}
+
+//+kubebuilder:rbac:groups=kuadrant.io,resources=dnsrecords,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=kuadrant.io,resources=dnsrecords/status,verbs=get;update;patch
+
+func (r *DNSHealthCheckReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	_ = log.FromContext(ctx)
+
+	previous := &v1.DNSRecord{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: req.Name}, previous)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			r.forget(req.NamespacedName)
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if previous.DeletionTimestamp != nil {
+		r.forget(req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
+
+	result, requeueAfter := r.probeResult(ctx, previous)
+	if result == nil {
+		// the record is reconciled again once its probes complete
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+	dnsRecord := previous.DeepCopy()
+
+	checks := map[string]v1.HealthCheckStatus{}
+	for _, check := range previous.Status.HealthChecks {
+		checks[check.SetIdentifier] = check
+	}
+	var statuses []v1.HealthCheckStatus
+	for _, endpoint := range dnsRecord.Spec.Endpoints {
+		if endpoint.Labels[trafficController.ClusterEndpointLabel] == "" {
+			// layer endpoints route to the cluster endpoints, which are
+			// checked themselves
+			continue
+		}
+		check, ok := checks[endpoint.SetIdentifier]
+		if !ok {
+			check = v1.HealthCheckStatus{SetIdentifier: endpoint.SetIdentifier, Healthy: true}
+		}
+		if probeErr, probed := result.errs[endpoint.SetIdentifier]; probed {
+			r.check(probeErr, &check, result.checked)
+		}
+		statuses = append(statuses, check)
+
+		if !check.Healthy && endpoint.Labels[trafficController.HealthEndpointLabel] != trafficController.UnhealthyEndpointValue {
+			log.Log.Info("draining unhealthy endpoint", "record", dnsRecord.Name, "endpoint", endpoint.SetIdentifier, "reason", check.Message)
+			if endpoint.Labels == nil {
+				endpoint.Labels = v1.Labels{}
+			}
+			weight, _ := endpoint.GetProviderSpecificProperty(dnsAWS.ProviderSpecificWeight)
+			endpoint.Labels[trafficController.HealthEndpointLabel] = trafficController.UnhealthyEndpointValue
+			endpoint.Labels[trafficController.DrainedWeightEndpointLabel] = weight.Value
+			endpoint.SetProviderSpecificProperty(dnsAWS.ProviderSpecificWeight, trafficController.UnhealthyWeight)
+		}
+		if check.Healthy && endpoint.Labels[trafficController.HealthEndpointLabel] == trafficController.UnhealthyEndpointValue {
+			log.Log.Info("restoring recovered endpoint", "record", dnsRecord.Name, "endpoint", endpoint.SetIdentifier)
+			weight := endpoint.Labels[trafficController.DrainedWeightEndpointLabel]
+			if weight == "" {
+				weight = trafficController.DefaultWeight
+			}
+			delete(endpoint.Labels, trafficController.HealthEndpointLabel)
+			delete(endpoint.Labels, trafficController.DrainedWeightEndpointLabel)
+			endpoint.SetProviderSpecificProperty(dnsAWS.ProviderSpecificWeight, weight)
+		}
+	}
+
+	if !equality.Semantic.DeepEqual(previous.Spec, dnsRecord.Spec) {
+		if err := r.Update(ctx, dnsRecord); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if !equality.Semantic.DeepEqual(previous.Status.HealthChecks, statuses) {
+		dnsRecord.Status.HealthChecks = statuses
+		if err := r.Status().Update(ctx, dnsRecord); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// probeResult returns the outcome of the completed probes of the record, or
+// starts probing the record in the background when it is due. Returns how
+// long until the record is due to be probed again.
+func (r *DNSHealthCheckReconciler) probeResult(ctx context.Context, record *v1.DNSRecord) (*probeResult, time.Duration) {
+	r.init()
+	key := client.ObjectKeyFromObject(record)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if result, ok := r.results[key]; ok {
+		delete(r.results, key)
+		return result, r.Interval
+	}
+	if r.probing[key] {
+		return nil, r.Interval
+	}
+	if since := clock.Since(r.lastProbed[key]); since < r.Interval {
+		return nil, r.Interval - since
+	}
+	r.probing[key] = true
+	r.lastProbed[key] = clock.Now()
+	go r.probe(ctx, record.DeepCopy())
+	return nil, r.Interval
+}
+
+// probe probes the endpoints of the record concurrently, stores the outcome
+// and triggers the reconcile of the record.
+func (r *DNSHealthCheckReconciler) probe(ctx context.Context, record *v1.DNSRecord) {
+	result := &probeResult{errs: map[string]error{}}
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for _, endpoint := range record.Spec.Endpoints {
+		if endpoint.Labels[trafficController.ClusterEndpointLabel] == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(endpoint *v1.Endpoint) {
+			defer wg.Done()
+			err := r.probeEndpoint(ctx, record.Name, endpoint)
+			lock.Lock()
+			defer lock.Unlock()
+			result.errs[endpoint.SetIdentifier] = err
+		}(endpoint)
+	}
+	wg.Wait()
+	result.checked = metav1.NewTime(clock.Now())
+
+	key := client.ObjectKeyFromObject(record)
+	r.lock.Lock()
+	delete(r.probing, key)
+	r.results[key] = result
+	r.lock.Unlock()
+
+	select {
+	case r.probed <- event.GenericEvent{Object: record}:
+	case <-ctx.Done():
+	}
+}
+
+// probeEndpoint probes every target of the endpoint for the host. The
+// endpoint fails the probe if any of its targets does.
+func (r *DNSHealthCheckReconciler) probeEndpoint(ctx context.Context, host string, endpoint *v1.Endpoint) error {
+	for _, target := range endpoint.Targets {
+		if err := r.Probe.Probe(ctx, host, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check records the outcome of a probe in the health check status. The time
+// of the probe is only recorded when the outcome changes the check, so
+// records are not updated on every probe.
+func (r *DNSHealthCheckReconciler) check(probeErr error, check *v1.HealthCheckStatus, checked metav1.Time) {
+	previous := *check
+	if probeErr != nil {
+		check.ConsecutiveFailures++
+		check.Message = probeErr.Error()
+		if check.ConsecutiveFailures >= r.failureThreshold() {
+			check.Healthy = false
+		}
+	} else {
+		check.ConsecutiveFailures = 0
+		check.Healthy = true
+		check.Message = "all targets passed the health check"
+	}
+	if !equality.Semantic.DeepEqual(previous, *check) {
+		check.LastChecked = checked
+	}
+}
+
+func (r *DNSHealthCheckReconciler) init() {
+	r.initOnce.Do(func() {
+		r.probing = map[types.NamespacedName]bool{}
+		r.lastProbed = map[types.NamespacedName]time.Time{}
+		r.results = map[types.NamespacedName]*probeResult{}
+		r.probed = make(chan event.GenericEvent)
+	})
+}
+
+// forget drops the probe state of a deleted record.
+func (r *DNSHealthCheckReconciler) forget(key types.NamespacedName) {
+	r.init()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.lastProbed, key)
+	delete(r.results, key)
+}
+
+func (r *DNSHealthCheckReconciler) failureThreshold() int {
+	if r.FailureThreshold == 0 {
+		return DefaultFailureThreshold
+	}
+	return r.FailureThreshold
+}
+
+// clock is to enable unit testing
+var clock utilclock.Clock = utilclock.RealClock{}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DNSHealthCheckReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Interval <= 0 {
+		return fmt.Errorf("the health check interval must be positive, got %v", r.Interval)
+	}
+	r.init()
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("dnshealth").
+		For(&v1.DNSRecord{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&source.Channel{Source: r.probed}, &handler.EnqueueRequestForObject{}).
+		WithEventFilter(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return obj.GetLabels()[trafficController.ManagedByLabel] == trafficController.ManagedByLabelValue && trafficController.IsOwned(obj, r.OwnerID)
+		})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(r)
+}
@@ -0,0 +1,168 @@
+package dnshealth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilclock "k8s.io/utils/clock"
+	testingclock "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	trafficController "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/traffic"
+	dnsAWS "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns/aws"
+)
+
+func testEndpoint(cluster, target string) *v1.Endpoint {
+	return (&v1.Endpoint{
+		DNSName:       "example.com",
+		Targets:       v1.Targets{target},
+		RecordType:    string(v1.ARecordType),
+		SetIdentifier: cluster,
+		Labels:        v1.Labels{trafficController.ClusterEndpointLabel: cluster},
+	}).WithProviderSpecific(dnsAWS.ProviderSpecificWeight, trafficController.DefaultWeight)
+}
+
+func TestDNSHealthCheckReconciler_Reconcile(t *testing.T) {
+	var status int32 = http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer server.Close()
+	target, port := testServer(t, server)
+
+	scheme := runtime.NewScheme()
+	if err := v1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	record := &v1.DNSRecord{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example.com",
+			Namespace: "test-control",
+			Labels:    map[string]string{trafficController.ManagedByLabel: trafficController.ManagedByLabelValue},
+		},
+		Spec: v1.DNSRecordSpec{
+			Endpoints: []*v1.Endpoint{testEndpoint("cluster-a", target), testEndpoint("cluster-b", "127.0.0.2")},
+		},
+	}
+	r := &DNSHealthCheckReconciler{
+		Client:           fake.NewClientBuilder().WithScheme(scheme).WithObjects(record).Build(),
+		Probe:            ProbeConfig{Port: port, Timeout: time.Second},
+		Interval:         time.Minute,
+		FailureThreshold: 2,
+	}
+	fakeClock := testingclock.NewFakeClock(time.Now().Truncate(time.Second))
+	clock = fakeClock
+	defer func() { clock = utilclock.RealClock{} }()
+
+	key := client.ObjectKeyFromObject(record)
+	reconcile := func() {
+		t.Helper()
+		result, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.RequeueAfter != time.Minute {
+			t.Errorf("expected requeue after '%v' got '%v'", time.Minute, result.RequeueAfter)
+		}
+		if err := r.Get(context.TODO(), key, record); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// probe starts the probes of the record in the background and applies
+	// their outcome once they complete
+	probe := func() {
+		t.Helper()
+		fakeClock.Step(time.Minute)
+		reconcile()
+		select {
+		case <-r.probed:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected the probes to complete")
+		}
+		reconcile()
+	}
+
+	// the probes run in the background, the record is left as it is until
+	// they complete
+	r.init()
+	result, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RequeueAfter != time.Minute {
+		t.Errorf("expected requeue after '%v' got '%v'", time.Minute, result.RequeueAfter)
+	}
+	<-r.probed
+	if err := r.Get(context.TODO(), key, record); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(record.Status.HealthChecks) != 0 {
+		t.Fatalf("expected no health checks before the probes complete, got: %v", record.Status.HealthChecks)
+	}
+	reconcile()
+	expectWeights := func(a, b string) {
+		t.Helper()
+		for i, expect := range []string{a, b} {
+			weight, _ := record.Spec.Endpoints[i].GetProviderSpecificProperty(dnsAWS.ProviderSpecificWeight)
+			if weight.Value != expect {
+				t.Errorf("expected endpoint %s to have weight '%v' got '%v'", record.Spec.Endpoints[i].SetIdentifier, expect, weight.Value)
+			}
+		}
+	}
+
+	// failures below the threshold leave the endpoints in place
+	expectWeights(trafficController.DefaultWeight, trafficController.DefaultWeight)
+	if len(record.Status.HealthChecks) != 2 || record.Status.HealthChecks[0].ConsecutiveFailures != 1 || !record.Status.HealthChecks[0].Healthy {
+		t.Fatalf("expected a failure to be recorded for each endpoint, got: %v", record.Status.HealthChecks)
+	}
+
+	// the record is not probed again before the interval
+	result, err = r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RequeueAfter != time.Minute {
+		t.Errorf("expected requeue after '%v' got '%v'", time.Minute, result.RequeueAfter)
+	}
+	select {
+	case <-r.probed:
+		t.Fatalf("expected the record not to be probed before the interval")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// both endpoints reach the threshold and are drained
+	probe()
+	expectWeights(trafficController.UnhealthyWeight, trafficController.UnhealthyWeight)
+	for _, check := range record.Status.HealthChecks {
+		if check.Healthy {
+			t.Errorf("expected endpoint %s to be unhealthy", check.SetIdentifier)
+		}
+	}
+
+	// cluster-a recovers
+	atomic.StoreInt32(&status, http.StatusOK)
+	probe()
+	expectWeights(trafficController.DefaultWeight, trafficController.UnhealthyWeight)
+	if _, ok := record.Spec.Endpoints[0].Labels[trafficController.HealthEndpointLabel]; ok {
+		t.Errorf("expected the health label to be removed from the recovered endpoint")
+	}
+	if !record.Status.HealthChecks[0].Healthy || record.Status.HealthChecks[0].ConsecutiveFailures != 0 {
+		t.Errorf("expected cluster-a to be healthy, got: %v", record.Status.HealthChecks[0])
+	}
+
+	// a probe that doesn't change the check of cluster-a leaves it as it is
+	lastChecked := record.Status.HealthChecks[0].LastChecked
+	probe()
+	if !record.Status.HealthChecks[0].LastChecked.Equal(&lastChecked) {
+		t.Errorf("expected the check of cluster-a to be left as it is, got: %v", record.Status.HealthChecks[0])
+	}
+}
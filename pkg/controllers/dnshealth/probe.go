@@ -0,0 +1,135 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dnshealth
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultPath             = "/"
+	DefaultExpectedStatus   = http.StatusOK
+	DefaultTimeout          = 5 * time.Second
+	DefaultFailureThreshold = 3
+)
+
+// ProbeConfig describes the health check issued against the targets of an
+// endpoint.
+type ProbeConfig struct {
+	// Path requested from the target
+	Path string
+	// Protocol is either "HTTP" or "HTTPS"
+	Protocol string
+	// Port the target is probed on, the default port of the protocol is used
+	// when zero
+	Port int
+	// ExpectedStatus is the status code of a healthy response
+	ExpectedStatus int
+	// Timeout of a single probe
+	Timeout time.Duration
+	// TLSConfig is the base TLS configuration of HTTPS probes, the server
+	// name is always set to the host being probed
+	TLSConfig *tls.Config
+}
+
+// Probe requests the host from the target address, sending the host in the
+// Host header and as the TLS server name so the target routes the request as
+// it would route a client resolving the host through DNS. Returns an error
+// when the target cannot be reached or responds with an unexpected status.
+func (c ProbeConfig) Probe(ctx context.Context, host, target string) error {
+	address := net.JoinHostPort(target, strconv.Itoa(c.port()))
+	dialer := &net.Dialer{}
+	tlsConfig := &tls.Config{}
+	if c.TLSConfig != nil {
+		tlsConfig = c.TLSConfig.Clone()
+	}
+	tlsConfig.ServerName = host
+	client := &http.Client{
+		Timeout: c.timeout(),
+		Transport: &http.Transport{
+			// connect to the target rather than resolving the host
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, address)
+			},
+			TLSClientConfig:   tlsConfig,
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	url := fmt.Sprintf("%s://%s%s", c.scheme(), net.JoinHostPort(host, strconv.Itoa(c.port())), c.path())
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != c.expectedStatus() {
+		return fmt.Errorf("%s responded with status %d, expected %d", target, response.StatusCode, c.expectedStatus())
+	}
+	return nil
+}
+
+func (c ProbeConfig) scheme() string {
+	if c.Protocol == "HTTPS" {
+		return "https"
+	}
+	return "http"
+}
+
+func (c ProbeConfig) port() int {
+	switch {
+	case c.Port != 0:
+		return c.Port
+	case c.Protocol == "HTTPS":
+		return 443
+	default:
+		return 80
+	}
+}
+
+func (c ProbeConfig) path() string {
+	if c.Path == "" {
+		return DefaultPath
+	}
+	return c.Path
+}
+
+func (c ProbeConfig) expectedStatus() int {
+	if c.ExpectedStatus == 0 {
+		return DefaultExpectedStatus
+	}
+	return c.ExpectedStatus
+}
+
+func (c ProbeConfig) timeout() time.Duration {
+	if c.Timeout == 0 {
+		return DefaultTimeout
+	}
+	return c.Timeout
+}
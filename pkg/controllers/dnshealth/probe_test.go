@@ -0,0 +1,95 @@
+package dnshealth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func testServer(t *testing.T, server *httptest.Server) (string, int) {
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return u.Hostname(), port
+}
+
+func TestProbeConfig_Probe(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Split(r.Host, ":")[0] != "example.com" || r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(handler)
+	defer tlsServer.Close()
+
+	target, port := testServer(t, server)
+	tlsTarget, tlsPort := testServer(t, tlsServer)
+	roots := x509.NewCertPool()
+	roots.AddCert(tlsServer.Certificate())
+
+	tests := []struct {
+		name      string
+		config    ProbeConfig
+		host      string
+		target    string
+		expectErr bool
+	}{
+		{
+			name:   "healthy HTTP target",
+			config: ProbeConfig{Path: "/healthz", Port: port},
+			host:   "example.com",
+			target: target,
+		},
+		{
+			name:   "healthy HTTPS target",
+			config: ProbeConfig{Path: "/healthz", Protocol: "HTTPS", Port: tlsPort, TLSConfig: &tls.Config{RootCAs: roots}},
+			host:   "example.com",
+			target: tlsTarget,
+		},
+		{
+			name:      "unexpected status",
+			config:    ProbeConfig{Path: "/other", Port: port},
+			host:      "example.com",
+			target:    target,
+			expectErr: true,
+		},
+		{
+			name:      "unexpected host",
+			config:    ProbeConfig{Path: "/healthz", Port: port},
+			host:      "other.example.com",
+			target:    target,
+			expectErr: true,
+		},
+		{
+			name:      "unreachable target",
+			config:    ProbeConfig{Path: "/healthz", Port: port},
+			host:      "example.com",
+			target:    "127.0.0.2",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Probe(context.TODO(), tt.host, tt.target)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("expected error '%v' got '%v'", tt.expectErr, err)
+			}
+		})
+	}
+}
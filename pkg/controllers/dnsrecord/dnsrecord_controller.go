@@ -22,20 +22,37 @@ import (
 	"os"
 	"reflect"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/record"
 	utilclock "k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	trafficController "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/traffic"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/tracing"
 )
 
 type ConditionStatus string
@@ -46,41 +63,79 @@ const (
 	ConditionTrue    ConditionStatus = "True"
 	ConditionFalse   ConditionStatus = "False"
 	ConditionUnknown ConditionStatus = "Unknown"
+
+	// The reasons of the events recorded on DNSRecords
+	PublishedReason     = "Published"
+	ReplacedReason      = "Replaced"
+	PublishFailedReason = "PublishFailed"
+	DeletedReason       = "Deleted"
+	DeleteFailedReason  = "DeleteFailed"
 )
 
 type DNSRecordReconcilerConfig struct {
 	DNSProvider string
+	// ProviderTimeout is the deadline of each call to the DNS provider,
+	// dns.DefaultTimeout when zero
+	ProviderTimeout time.Duration
+	// Zones are the zones the records are published to. The zone of the
+	// AWS_DNS_PUBLIC_ZONE_ID environment variable is used when empty.
+	Zones []v1.DNSZone
+	// OwnerID restricts the records published to those labelled with it,
+	// every record is published when empty
+	OwnerID string
 }
 
 // DNSRecordReconciler reconciles a DNSRecord object
 type DNSRecordReconciler struct {
 	client.Client
 	Scheme           *runtime.Scheme
+	Recorder         record.EventRecorder
 	ReconcilerConfig DNSRecordReconcilerConfig
-	DNSProvider      dns.Provider
-	DNSZones         []v1.DNSZone
+	// DNSProvider manages the zones that don't reference a credentials
+	// secret, with the credentials of the environment
+	DNSProvider dns.Provider
+	// Providers manage the zones that reference a credentials secret
+	Providers *dns.ProviderCache
+	DNSZones  []v1.DNSZone
+
+	// MaxConcurrentReconciles is the number of objects reconciled
+	// concurrently, one when zero
+	MaxConcurrentReconciles int
+
+	metricsOnce   sync.Once
+	recordMetrics *recordMetrics
 }
 
 //+kubebuilder:rbac:groups=kuadrant.io,resources=dnsrecords,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=kuadrant.io,resources=dnsrecords/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=kuadrant.io,resources=dnsrecords/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 
-func (r *DNSRecordReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *DNSRecordReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
 	_ = log.FromContext(ctx)
+	ctx, span := tracing.Tracer().Start(ctx, "DNSRecordReconciler.Reconcile", trace.WithAttributes(
+		attribute.String("dnsrecord.namespace", req.Namespace),
+		attribute.String("dnsrecord.name", req.Name),
+	))
+	defer func() { tracing.End(span, err) }()
 
 	previous := &v1.DNSRecord{}
-	err := r.Client.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: req.Name}, previous)
+	err = r.Client.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: req.Name}, previous)
 	if err != nil {
 		if err := client.IgnoreNotFound(err); err == nil {
+			r.metrics().set(req.NamespacedName, nil)
 			return ctrl.Result{}, nil
 		} else {
 			return ctrl.Result{}, err
 		}
 	}
+	if !trafficController.IsOwned(previous, r.ReconcilerConfig.OwnerID) {
+		return ctrl.Result{}, nil
+	}
 	dnsRecord := previous.DeepCopy()
 
 	if dnsRecord.DeletionTimestamp != nil && !dnsRecord.DeletionTimestamp.IsZero() {
-		if err := r.deleteRecord(dnsRecord); err != nil && !strings.Contains(err.Error(), "was not found") {
+		if err := r.deleteRecord(ctx, dnsRecord); err != nil && !strings.Contains(err.Error(), "was not found") {
 			log.Log.Error(err, "Failed to delete DNSRecord", "record", dnsRecord)
 			return ctrl.Result{}, err
 		}
@@ -90,6 +145,7 @@ func (r *DNSRecordReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		if err != nil {
 			return ctrl.Result{}, err
 		}
+		r.metrics().set(req.NamespacedName, nil)
 		return ctrl.Result{}, nil
 	}
 
@@ -101,7 +157,7 @@ func (r *DNSRecordReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		}
 	}
 
-	statuses := r.publishRecordToZones(r.DNSZones, dnsRecord)
+	statuses := r.publishRecordToZones(ctx, r.DNSZones, dnsRecord)
 	if !dnsZoneStatusSlicesEqual(statuses, dnsRecord.Status.Zones) || dnsRecord.Status.ObservedGeneration != dnsRecord.Generation {
 		dnsRecord.Status.Zones = statuses
 		dnsRecord.Status.ObservedGeneration = dnsRecord.Generation
@@ -111,54 +167,149 @@ func (r *DNSRecordReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	if err != nil {
 		return ctrl.Result{}, err
 	}
+	r.metrics().set(req.NamespacedName, dnsRecord.Status.Zones)
 
 	return ctrl.Result{}, nil
 }
 
+func (r *DNSRecordReconciler) metrics() *recordMetrics {
+	r.metricsOnce.Do(func() {
+		r.recordMetrics = newRecordMetrics(r.ReconcilerConfig.DNSProvider)
+	})
+	return r.recordMetrics
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *DNSRecordReconciler) SetupWithManager(mgr ctrl.Manager) error {
-
-	dnsProvider, err := dns.DNSProvider(r.ReconcilerConfig.DNSProvider)
-	if err != nil {
-		return err
+	timeout := r.ReconcilerConfig.ProviderTimeout
+	if timeout == 0 {
+		timeout = dns.DefaultTimeout
 	}
-	r.DNSProvider = dnsProvider
 
-	var dnsZones []v1.DNSZone
-	zoneID, zoneIDSet := os.LookupEnv("AWS_DNS_PUBLIC_ZONE_ID")
-	if zoneIDSet {
-		dnsZone := &v1.DNSZone{
-			ID: zoneID,
+	dnsZones := r.ReconcilerConfig.Zones
+	if len(dnsZones) == 0 {
+		zoneID, zoneIDSet := os.LookupEnv("AWS_DNS_PUBLIC_ZONE_ID")
+		if zoneIDSet {
+			dnsZones = append(dnsZones, v1.DNSZone{ID: zoneID})
+		} else {
+			log.Log.Info("No DNS zones configured and no AWS DNS zone id set (AWS_DNS_PUBLIC_ZONE_ID), no DNS records will be created!")
 		}
-		dnsZones = append(dnsZones, *dnsZone)
-		log.Log.Info("Using AWS DNS zone", "id", zoneID)
-	} else {
-		log.Log.Info("No AWS DNS zone id set (AWS_DNS_PUBLIC_ZONE_ID), no DNS records will be created!")
 	}
 	r.DNSZones = dnsZones
 
-	//Logging state of AWS credentials
-	awsIdKey := os.Getenv("AWS_ACCESS_KEY_ID")
-	if awsIdKey != "" {
-		log.Log.Info("AWS Access Key set")
-	} else {
-		log.Log.Info("AWS Access Key is NOT set")
+	environmentCredentials := false
+	for _, zone := range dnsZones {
+		if zone.CredentialsSecretRef == nil {
+			environmentCredentials = true
+			log.Log.Info("Using DNS zone with the credentials of the environment", "id", zone.ID)
+		} else {
+			log.Log.Info("Using DNS zone with the credentials of a secret", "id", zone.ID, "secret", zone.CredentialsSecretRef)
+		}
 	}
-
-	awsSecretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
-	if awsSecretKey != "" {
-		log.Log.Info("AWS Secret Key set")
-	} else {
-		log.Log.Info("AWS Secret Key is NOT set")
+	// the provider of the environment is only created when it is used, the
+	// environment may hold no credentials
+	if environmentCredentials {
+		dnsProvider, err := dns.DNSProvider(r.ReconcilerConfig.DNSProvider)
+		if err != nil {
+			return err
+		}
+		r.DNSProvider = dns.WithTimeout(dnsProvider, timeout)
+	}
+	r.Providers = &dns.ProviderCache{
+		New: func(credentials map[string][]byte) (dns.Provider, error) {
+			provider, err := dns.DNSProviderForCredentials(r.ReconcilerConfig.DNSProvider, credentials)
+			if err != nil {
+				return nil, err
+			}
+			return dns.WithTimeout(provider, timeout), nil
+		},
 	}
 
+	owned := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return trafficController.IsOwned(obj, r.ReconcilerConfig.OwnerID)
+	})
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1.DNSRecord{}).
+		// status updates, such as those of the health checks, don't need the
+		// record to be published again
+		For(&v1.DNSRecord{}, builder.WithPredicates(owned, predicate.Or(predicate.GenerationChangedPredicate{}, predicate.LabelChangedPredicate{}))).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.recordsForCredentials)).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
 		Complete(r)
 }
 
-func (r *DNSRecordReconciler) publishRecordToZones(zones []v1.DNSZone, record *v1.DNSRecord) []v1.DNSZoneStatus {
+// CheckProviders is a readiness check failing when the provider of the
+// credentials of the environment can't reach its DNS service, while zones
+// are published with it. The providers of zones with their own credentials
+// aren't checked, a zone with bad credentials fails to publish on its own
+// rather than making the controller unready.
+func (r *DNSRecordReconciler) CheckProviders(ctx context.Context) error {
+	if r.DNSProvider == nil {
+		return nil
+	}
+	for _, zone := range r.DNSZones {
+		if zone.CredentialsSecretRef == nil {
+			return dns.Check(ctx, r.DNSProvider)
+		}
+	}
+	return nil
+}
+
+// recordsForCredentials returns the requests of every owned record when the secret
+// holds the credentials of a zone, so records that failed to publish are
+// published with the changed credentials.
+func (r *DNSRecordReconciler) recordsForCredentials(obj client.Object) []reconcile.Request {
+	referenced := false
+	for _, zone := range r.DNSZones {
+		ref := zone.CredentialsSecretRef
+		if ref != nil && ref.Namespace == obj.GetNamespace() && ref.Name == obj.GetName() {
+			referenced = true
+		}
+	}
+	if !referenced {
+		return nil
+	}
+	records := &v1.DNSRecordList{}
+	if err := r.Client.List(context.TODO(), records); err != nil {
+		log.Log.Error(err, "failed to list DNSRecords for changed credentials", "secret", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, record := range records.Items {
+		if !trafficController.IsOwned(&record, r.ReconcilerConfig.OwnerID) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&record)})
+	}
+	return requests
+}
+
+// providerFor returns the provider managing the zone, with the credentials of
+// the secret the zone references or the credentials of the environment.
+func (r *DNSRecordReconciler) providerFor(ctx context.Context, zone v1.DNSZone) (dns.Provider, error) {
+	ref := zone.CredentialsSecretRef
+	if ref == nil {
+		if r.DNSProvider == nil {
+			return nil, fmt.Errorf("no DNS provider for zone %s without credentials", zone.ID)
+		}
+		return r.DNSProvider, nil
+	}
+	if r.Providers == nil {
+		return nil, fmt.Errorf("no DNS provider for zone %s with credentials", zone.ID)
+	}
+	key := types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}
+	secret := &corev1.Secret{}
+	if err := r.Client.Get(ctx, key, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.Providers.Forget(key)
+		}
+		return nil, fmt.Errorf("failed to get the credentials of zone %s: %w", zone.ID, err)
+	}
+	return r.Providers.Get(key, secret.Data)
+}
+
+func (r *DNSRecordReconciler) publishRecordToZones(ctx context.Context, zones []v1.DNSZone, record *v1.DNSRecord) []v1.DNSZoneStatus {
 	var statuses []v1.DNSZoneStatus
+	specChanged := r.metrics().specChanged(record)
 	for i := range zones {
 		zone := zones[i]
 
@@ -176,33 +327,52 @@ func (r *DNSRecordReconciler) publishRecordToZones(zones []v1.DNSZone, record *v
 			LastTransitionTime: metav1.Now(),
 		}
 
-		if recordIsAlreadyPublishedToZone(record, &zone) {
-			log.Log.Info("replacing DNS record", "record", record, "zone", zone)
-
-			if err := r.DNSProvider.Ensure(record, zone); err != nil {
+		// replacing a published record only changes the zone when the
+		// endpoints published to it differ from the spec
+		replacing := recordIsAlreadyPublishedToZone(record, &zone)
+		changed := !replacing || !endpointsPublishedToZone(record, &zone)
+		if replacing {
+			log.Log.Info("replacing DNS record", "record", record, "zone", zone, "changed", changed)
+		}
+		err := r.providerCall(ctx, "dns.Provider.Ensure", zone, func(ctx context.Context) error {
+			provider, err := r.providerFor(ctx, zone)
+			if err != nil {
+				return err
+			}
+			return provider.Ensure(ctx, record, zone)
+		})
+		if replacing {
+			if err != nil {
 				log.Log.Error(err, "Failed to replace DNS record in zone", "record", record.Spec, "zone", zone)
+				r.Recorder.Eventf(record, corev1.EventTypeWarning, PublishFailedReason, "Failed to replace the record in zone %v: %v", zone.ID, err)
 				condition.Status = string(ConditionTrue)
 				condition.Reason = "ProviderError"
 				condition.Message = fmt.Sprintf("The DNS provider failed to replace the record: %v", err)
 			} else {
 				log.Log.Info("Replaced DNS record in zone", "record", record.Spec, "zone", zone)
+				if changed {
+					r.Recorder.Eventf(record, corev1.EventTypeNormal, ReplacedReason, "Replaced the record in zone %v with the changed endpoints", zone.ID)
+				}
 				condition.Status = string(ConditionFalse)
 				condition.Reason = "ProviderSuccess"
 				condition.Message = "The DNS provider succeeded in replacing the record"
 			}
 		} else {
-			if err := r.DNSProvider.Ensure(record, zone); err != nil {
+			if err != nil {
 				log.Log.Error(err, "Failed to publish DNS record to zone", "record", record.Spec, "zone", zone)
+				r.Recorder.Eventf(record, corev1.EventTypeWarning, PublishFailedReason, "Failed to publish the record to zone %v: %v", zone.ID, err)
 				condition.Status = string(ConditionTrue)
 				condition.Reason = "ProviderError"
 				condition.Message = fmt.Sprintf("The DNS provider failed to ensure the record: %v", err)
 			} else {
 				log.Log.Info("Published DNS record to zone", "record", record.Spec, "zone", zone)
+				r.Recorder.Eventf(record, corev1.EventTypeNormal, PublishedReason, "Published the record to zone %v", zone.ID)
 				condition.Status = string(ConditionFalse)
 				condition.Reason = "ProviderSuccess"
 				condition.Message = "The DNS provider succeeded in ensuring the record"
 			}
 		}
+		r.observePublish(record, zone, replacing, specChanged, condition, err)
 		statuses = append(statuses, v1.DNSZoneStatus{
 			DNSZone:    zone,
 			Conditions: []v1.DNSZoneCondition{condition},
@@ -212,7 +382,40 @@ func (r *DNSRecordReconciler) publishRecordToZones(zones []v1.DNSZone, record *v
 	return mergeStatuses(zones, record.Status.DeepCopy().Zones, statuses)
 }
 
-func (r *DNSRecordReconciler) deleteRecord(record *v1.DNSRecord) error {
+// observePublish updates the publish metrics for an attempt to publish the
+// record to the zone.
+func (r *DNSRecordReconciler) observePublish(record *v1.DNSRecord, zone v1.DNSZone, replacing bool, specChanged time.Time, condition v1.DNSZoneCondition, err error) {
+	provider := r.ReconcilerConfig.DNSProvider
+	if err != nil {
+		publishTotal.WithLabelValues(provider, zone.ID, resultFailure, failureReason(err, condition.Reason)).Inc()
+		return
+	}
+	publishTotal.WithLabelValues(provider, zone.ID, resultSuccess, condition.Reason).Inc()
+	if r.metrics().publishedTo(record, zone.ID) {
+		publishLatency.WithLabelValues(provider, zone.ID).Observe(clock.Since(specChanged).Seconds())
+	}
+	if !replacing {
+		return
+	}
+	for _, status := range record.Status.Zones {
+		if reflect.DeepEqual(status.DNSZone, zone) {
+			staleEndpointDeletions.WithLabelValues(provider, zone.ID).Add(float64(staleEndpoints(status.Endpoints, record.Spec.Endpoints)))
+		}
+	}
+}
+
+// providerCall calls the DNS provider for the zone within a span.
+func (r *DNSRecordReconciler) providerCall(ctx context.Context, name string, zone v1.DNSZone, f func(context.Context) error) error {
+	ctx, span := tracing.Tracer().Start(ctx, name, trace.WithAttributes(
+		attribute.String("dns.provider", r.ReconcilerConfig.DNSProvider),
+		attribute.String("dns.zone", zone.ID),
+	))
+	err := f(ctx)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *DNSRecordReconciler) deleteRecord(ctx context.Context, record *v1.DNSRecord) error {
 	var errs []error
 	for i := range record.Status.Zones {
 		zone := record.Status.Zones[i].DNSZone
@@ -221,11 +424,19 @@ func (r *DNSRecordReconciler) deleteRecord(record *v1.DNSRecord) error {
 		if !recordIsAlreadyPublishedToZone(record, &zone) {
 			continue
 		}
-		err := r.DNSProvider.Delete(record, zone)
+		err := r.providerCall(ctx, "dns.Provider.Delete", zone, func(ctx context.Context) error {
+			provider, err := r.providerFor(ctx, zone)
+			if err != nil {
+				return err
+			}
+			return provider.Delete(ctx, record, zone)
+		})
 		if err != nil {
 			errs = append(errs, err)
+			r.Recorder.Eventf(record, corev1.EventTypeWarning, DeleteFailedReason, "Failed to delete the record from zone %v: %v", zone.ID, err)
 		} else {
 			log.Log.Info("Deleted DNSRecord from DNS provider", "record", record.Spec, "zone", zone)
+			r.Recorder.Eventf(record, corev1.EventTypeNormal, DeletedReason, "Deleted the record from zone %v", zone.ID)
 		}
 	}
 	if len(errs) == 0 {
@@ -253,6 +464,17 @@ func recordIsAlreadyPublishedToZone(record *v1.DNSRecord, zoneToPublish *v1.DNSZ
 	return false
 }
 
+// endpointsPublishedToZone returns whether the endpoints in the status of
+// the zone are the endpoints of the spec.
+func endpointsPublishedToZone(record *v1.DNSRecord, zoneToPublish *v1.DNSZone) bool {
+	for _, zoneInStatus := range record.Status.Zones {
+		if reflect.DeepEqual(&zoneInStatus.DNSZone, zoneToPublish) {
+			return equality.Semantic.DeepEqual(zoneInStatus.Endpoints, record.Spec.Endpoints)
+		}
+	}
+	return false
+}
+
 // mergeStatuses updates or extends the provided slice of statuses with the
 // provided updates and returns the resulting slice.
 func mergeStatuses(zones []v1.DNSZone, statuses, updates []v1.DNSZoneStatus) []v1.DNSZoneStatus {
@@ -0,0 +1,271 @@
+package dnsrecord
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	utilclock "k8s.io/utils/clock"
+	clocktesting "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	trafficController "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/traffic"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/tracing"
+)
+
+type testProvider struct {
+	err error
+}
+
+func (p *testProvider) Ensure(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone) error {
+	return p.err
+}
+func (p *testProvider) Delete(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone) error {
+	return p.err
+}
+
+func (p *testProvider) Check(ctx context.Context) error {
+	return p.err
+}
+
+// latencySamples returns the number of publish latencies observed for the
+// zone.
+func latencySamples(t *testing.T, zone string) float64 {
+	t.Helper()
+	metric := &dto.Metric{}
+	if err := publishLatency.WithLabelValues("test", zone).(prometheus.Histogram).Write(metric); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return float64(metric.GetHistogram().GetSampleCount())
+}
+
+func TestPublishRecordToZones(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	clock = fakeClock
+	defer func() { clock = utilclock.RealClock{} }()
+
+	provider := &testProvider{}
+	recorder := record.NewFakeRecorder(10)
+	r := &DNSRecordReconciler{
+		Recorder:         recorder,
+		ReconcilerConfig: DNSRecordReconcilerConfig{DNSProvider: "test"},
+		DNSProvider:      provider,
+	}
+	zone := v1.DNSZone{ID: "test-zone"}
+	key := types.NamespacedName{Namespace: "test-namespace", Name: "test.example.com"}
+	record := &v1.DNSRecord{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              key.Name,
+			Namespace:         key.Namespace,
+			Generation:        1,
+			CreationTimestamp: metav1.NewTime(fakeClock.Now()),
+		},
+		Spec: v1.DNSRecordSpec{Endpoints: []*v1.Endpoint{
+			{DNSName: "test.example.com", SetIdentifier: "a", RecordType: "A"},
+			{DNSName: "test.example.com", SetIdentifier: "b", RecordType: "A"},
+		}},
+	}
+
+	expect := func(name string, actual, expected float64) {
+		t.Helper()
+		if actual != expected {
+			t.Errorf("expected %v %v got %v", name, expected, actual)
+		}
+	}
+	expectEvent := func(prefix string) {
+		t.Helper()
+		select {
+		case event := <-recorder.Events:
+			if !strings.HasPrefix(event, prefix) {
+				t.Errorf("expected event '%v' got '%v'", prefix, event)
+			}
+		default:
+			t.Errorf("expected event '%v'", prefix)
+		}
+	}
+
+	// the first attempt fails with a provider error code
+	provider.err = awserr.New("Throttling", "rate exceeded", errors.New("throttled"))
+	record.Status.Zones = r.publishRecordToZones(context.TODO(), []v1.DNSZone{zone}, record)
+	r.metrics().set(key, record.Status.Zones)
+	expect("failures", testutil.ToFloat64(publishTotal.WithLabelValues("test", "test-zone", resultFailure, "Throttling")), 1)
+	expect("unpublished records", testutil.ToFloat64(recordsPerZone.WithLabelValues("test", "test-zone", "false")), 1)
+	expectEvent("Warning " + PublishFailedReason)
+
+	// the retry succeeds 30 seconds after the record was created
+	provider.err = nil
+	fakeClock.Step(30 * time.Second)
+	record.Status.Zones = r.publishRecordToZones(context.TODO(), []v1.DNSZone{zone}, record)
+	record.Status.ObservedGeneration = record.Generation
+	r.metrics().set(key, record.Status.Zones)
+	expect("successes", testutil.ToFloat64(publishTotal.WithLabelValues("test", "test-zone", resultSuccess, "ProviderSuccess")), 1)
+	expect("published records", testutil.ToFloat64(recordsPerZone.WithLabelValues("test", "test-zone", "true")), 1)
+	expect("unpublished records", testutil.ToFloat64(recordsPerZone.WithLabelValues("test", "test-zone", "false")), 0)
+	expect("published endpoints", testutil.ToFloat64(publishedEndpoints.WithLabelValues("test", "test-zone")), 2)
+	expect("latency observations", latencySamples(t, "test-zone"), 1)
+	expectEvent("Normal " + PublishedReason)
+
+	// publishing the same generation again is not observed again
+	record.Status.Zones = nil
+	record.Status.Zones = r.publishRecordToZones(context.TODO(), []v1.DNSZone{zone}, record)
+	expect("latency observations", latencySamples(t, "test-zone"), 1)
+	expectEvent("Normal " + PublishedReason)
+
+	// an endpoint is removed from the record
+	record.Generation = 2
+	record.Spec.Endpoints = record.Spec.Endpoints[:1]
+	record.Status.Zones = r.publishRecordToZones(context.TODO(), []v1.DNSZone{zone}, record)
+	r.metrics().set(key, record.Status.Zones)
+	expect("stale endpoint deletions", testutil.ToFloat64(staleEndpointDeletions.WithLabelValues("test", "test-zone")), 1)
+	expectEvent("Normal " + ReplacedReason)
+	expect("published endpoints", testutil.ToFloat64(publishedEndpoints.WithLabelValues("test", "test-zone")), 1)
+
+	// a change of the spec that leaves the endpoints as they are records no
+	// event
+	record.Status.ObservedGeneration = record.Generation
+	record.Generation = 3
+	record.Status.Zones = r.publishRecordToZones(context.TODO(), []v1.DNSZone{zone}, record)
+	select {
+	case event := <-recorder.Events:
+		t.Errorf("expected no event got '%v'", event)
+	default:
+	}
+
+	// the record is deleted
+	r.metrics().set(key, nil)
+	expect("published records", testutil.ToFloat64(recordsPerZone.WithLabelValues("test", "test-zone", "true")), 0)
+	expect("published endpoints", testutil.ToFloat64(publishedEndpoints.WithLabelValues("test", "test-zone")), 0)
+}
+
+func TestPublishRecordToZonesTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer otel.SetTracerProvider(previous)
+
+	r := &DNSRecordReconciler{
+		Recorder:         record.NewFakeRecorder(10),
+		ReconcilerConfig: DNSRecordReconcilerConfig{DNSProvider: "test"},
+		DNSProvider:      &testProvider{err: errors.New("unavailable")},
+	}
+	record := &v1.DNSRecord{ObjectMeta: metav1.ObjectMeta{Name: "test.example.com", Namespace: "test-namespace", Generation: 1}}
+
+	ctx, parent := tracing.Tracer().Start(context.TODO(), "parent")
+	r.publishRecordToZones(ctx, []v1.DNSZone{{ID: "zone-a"}, {ID: "zone-b"}}, record)
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans got %v", len(spans))
+	}
+	for i, zone := range []string{"zone-a", "zone-b"} {
+		span := spans[i]
+		if span.Name != "dns.Provider.Ensure" {
+			t.Errorf("expected span 'dns.Provider.Ensure' got '%v'", span.Name)
+		}
+		if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("expected span of zone %v to be a child of the reconcile span", zone)
+		}
+		if span.Status.Code != codes.Error {
+			t.Errorf("expected span of zone %v to have status %v got %v", zone, codes.Error, span.Status.Code)
+		}
+		found := false
+		for _, a := range span.Attributes {
+			if a == attribute.String("dns.zone", zone) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected span to have zone attribute '%v' got %v", zone, span.Attributes)
+		}
+	}
+}
+
+func TestCheckProviders(t *testing.T) {
+	provider := &testProvider{err: errors.New("unreachable")}
+	r := &DNSRecordReconciler{
+		DNSProvider: provider,
+		DNSZones: []v1.DNSZone{
+			{ID: "tenant-zone", CredentialsSecretRef: &v1.SecretReference{Namespace: "tenant", Name: "credentials"}},
+		},
+	}
+
+	// the provider of the environment is not checked while no zone uses it
+	if err := r.CheckProviders(context.TODO()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	r.DNSZones = append(r.DNSZones, v1.DNSZone{ID: "test-zone"})
+	if err := r.CheckProviders(context.TODO()); err != provider.err {
+		t.Errorf("expected error '%v' got '%v'", provider.err, err)
+	}
+}
+
+func TestRecordsForCredentials(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := v1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	owned := &v1.DNSRecord{ObjectMeta: metav1.ObjectMeta{
+		Name:      "owned.example.com",
+		Namespace: "test-namespace",
+		Labels:    map[string]string{trafficController.OwnerIDLabel: "owner-1"},
+	}}
+	other := &v1.DNSRecord{ObjectMeta: metav1.ObjectMeta{
+		Name:      "other.example.com",
+		Namespace: "test-namespace",
+		Labels:    map[string]string{trafficController.OwnerIDLabel: "owner-2"},
+	}}
+	unlabelled := &v1.DNSRecord{ObjectMeta: metav1.ObjectMeta{
+		Name:      "unlabelled.example.com",
+		Namespace: "test-namespace",
+	}}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "tenant", Name: "credentials"}}
+	r := &DNSRecordReconciler{
+		Client:           fake.NewClientBuilder().WithScheme(scheme).WithObjects(owned, other, unlabelled).Build(),
+		ReconcilerConfig: DNSRecordReconcilerConfig{OwnerID: "owner-1"},
+		DNSZones: []v1.DNSZone{
+			{ID: "tenant-zone", CredentialsSecretRef: &v1.SecretReference{Namespace: "tenant", Name: "credentials"}},
+		},
+	}
+
+	// only the records of the owner are published with the changed credentials
+	requests := r.recordsForCredentials(secret)
+	if len(requests) != 1 || requests[0].NamespacedName != client.ObjectKeyFromObject(owned) {
+		t.Errorf("expected only the owned record got %v", requests)
+	}
+
+	// the records of other owners are not reconciled
+	for _, record := range []*v1.DNSRecord{other, unlabelled} {
+		req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(record)}
+		if _, err := r.Reconcile(context.TODO(), req); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		reconciled := &v1.DNSRecord{}
+		if err := r.Client.Get(context.TODO(), req.NamespacedName, reconciled); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(reconciled.Finalizers) != 0 || len(reconciled.Status.Zones) != 0 {
+			t.Errorf("expected record %v not to be reconciled", req.NamespacedName)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dnsrecord
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+const (
+	providerLabel  = "provider"
+	zoneLabel      = "zone"
+	publishedLabel = "published"
+	resultLabel    = "result"
+	reasonLabel    = "reason"
+
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+var (
+	// recordsPerZone is a prometheus metric which holds the number of
+	// DNSRecords in each zone, by whether they are published.
+	recordsPerZone = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mctc_dns_records",
+			Help: "MCTC number of DNSRecords in each zone",
+		},
+		[]string{providerLabel, zoneLabel, publishedLabel},
+	)
+
+	// publishedEndpoints is a prometheus metric which holds the number of
+	// endpoints published to each zone.
+	publishedEndpoints = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mctc_dns_record_published_endpoints",
+			Help: "MCTC number of DNSRecord endpoints published to each zone",
+		},
+		[]string{providerLabel, zoneLabel},
+	)
+
+	// publishTotal is a prometheus counter metrics which holds the total
+	// number of attempts to publish DNSRecords to a zone, by result and
+	// reason. The reason of a failure is the error code of the provider when
+	// it has one.
+	publishTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mctc_dns_record_publish_total",
+			Help: "MCTC total number of attempts to publish DNSRecords to a zone",
+		},
+		[]string{providerLabel, zoneLabel, resultLabel, reasonLabel},
+	)
+
+	// publishLatency is a prometheus metric which records the time from a
+	// change of the spec of a DNSRecord to its successful publishing.
+	publishLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "mctc_dns_record_publish_latency_seconds",
+			Help: "MCTC time from a change of a DNSRecord to its successful publishing",
+			Buckets: []float64{
+				0.1, 0.25, 0.5, 1, 2.5, 5, 10, 15, 30, 60, 120, 300, 600, 1800, 3600,
+			},
+		},
+		[]string{providerLabel, zoneLabel},
+	)
+
+	// staleEndpointDeletions is a prometheus counter metrics which holds the
+	// total number of endpoints removed from a zone because they were removed
+	// from their DNSRecord.
+	staleEndpointDeletions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mctc_dns_record_stale_endpoint_deletions_total",
+			Help: "MCTC total number of stale DNSRecord endpoints deleted from a zone",
+		},
+		[]string{providerLabel, zoneLabel},
+	)
+)
+
+func init() {
+	// Register metrics into the global prometheus registry
+	metrics.Registry.MustRegister(
+		recordsPerZone,
+		publishedEndpoints,
+		publishTotal,
+		publishLatency,
+		staleEndpointDeletions,
+	)
+}
+
+// failureReason returns the error code of a provider error, or the given
+// reason when the error has none.
+func failureReason(err error, reason string) string {
+	var coded interface{ Code() string }
+	if errors.As(err, &coded) && coded.Code() != "" {
+		return coded.Code()
+	}
+	return reason
+}
+
+// staleEndpoints returns the number of endpoints published before that are
+// not in the endpoints being published.
+func staleEndpoints(published, endpoints []*v1.Endpoint) int {
+	stale := 0
+	for _, old := range published {
+		found := false
+		for _, endpoint := range endpoints {
+			if old.DNSName == endpoint.DNSName && old.SetIdentifier == endpoint.SetIdentifier && old.RecordType == endpoint.RecordType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			stale++
+		}
+	}
+	return stale
+}
+
+// recordMetrics tracks the state of the DNSRecords in each zone to compute
+// the record level gauges, and the time the spec of each record changed.
+type recordMetrics struct {
+	lock     sync.Mutex
+	provider string
+	records  map[types.NamespacedName][]v1.DNSZoneStatus
+	changes  map[types.NamespacedName]specChange
+	// zones are the zones gauges were set for, so they are reset when the
+	// last record leaves a zone
+	zones map[string]bool
+}
+
+type specChange struct {
+	generation int64
+	time       time.Time
+	// published holds the zones the generation was published to
+	published map[string]bool
+}
+
+func newRecordMetrics(provider string) *recordMetrics {
+	return &recordMetrics{
+		provider: provider,
+		records:  map[types.NamespacedName][]v1.DNSZoneStatus{},
+		changes:  map[types.NamespacedName]specChange{},
+		zones:    map[string]bool{},
+	}
+}
+
+// specChanged returns when the current generation of the record was first
+// observed, or created for the first generation.
+func (m *recordMetrics) specChanged(record *v1.DNSRecord) time.Time {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	key := types.NamespacedName{Namespace: record.Namespace, Name: record.Name}
+	change, ok := m.changes[key]
+	if !ok || change.generation != record.Generation {
+		change = specChange{generation: record.Generation, time: clock.Now(), published: map[string]bool{}}
+		if record.Generation <= 1 && !record.CreationTimestamp.IsZero() {
+			change.time = record.CreationTimestamp.Time
+		}
+		m.changes[key] = change
+	}
+	return change.time
+}
+
+// publishedTo records that the current generation of the record was
+// published to the zone. Returns false when it already was, so the publish
+// latency is observed once per generation.
+func (m *recordMetrics) publishedTo(record *v1.DNSRecord, zone string) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	key := types.NamespacedName{Namespace: record.Namespace, Name: record.Name}
+	change, ok := m.changes[key]
+	if !ok || change.generation != record.Generation || change.published[zone] {
+		return false
+	}
+	change.published[zone] = true
+	return true
+}
+
+// set records the zone statuses of the record, nil when it was deleted, and
+// updates the record level gauges.
+func (m *recordMetrics) set(key types.NamespacedName, zones []v1.DNSZoneStatus) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if zones == nil {
+		delete(m.records, key)
+		delete(m.changes, key)
+	} else {
+		m.records[key] = zones
+	}
+
+	records := map[string]map[bool]int{}
+	endpoints := map[string]int{}
+	for _, statuses := range m.records {
+		for _, status := range statuses {
+			zone := status.DNSZone.ID
+			published := isPublished(status)
+			if records[zone] == nil {
+				records[zone] = map[bool]int{}
+			}
+			records[zone][published]++
+			if published {
+				endpoints[zone] += len(status.Endpoints)
+			}
+		}
+	}
+	for zone := range records {
+		m.zones[zone] = true
+	}
+	for zone := range m.zones {
+		recordsPerZone.WithLabelValues(m.provider, zone, "true").Set(float64(records[zone][true]))
+		recordsPerZone.WithLabelValues(m.provider, zone, "false").Set(float64(records[zone][false]))
+		publishedEndpoints.WithLabelValues(m.provider, zone).Set(float64(endpoints[zone]))
+	}
+}
+
+func isPublished(status v1.DNSZoneStatus) bool {
+	for _, condition := range status.Conditions {
+		if condition.Type == v1.DNSRecordFailedConditionType {
+			return condition.Status == string(ConditionFalse)
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dnsrecord
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+// ParseZones parses a comma separated list of zones. A zone is its ID,
+// followed by '=' and the namespace/name of its credentials secret when the
+// zone is managed with the credentials of a secret.
+func ParseZones(zones string) ([]v1.DNSZone, error) {
+	var parsed []v1.DNSZone
+	for _, zone := range strings.Split(zones, ",") {
+		if zone = strings.TrimSpace(zone); zone == "" {
+			continue
+		}
+		id, secret, hasSecret := strings.Cut(zone, "=")
+		dnsZone := v1.DNSZone{ID: strings.TrimSpace(id)}
+		if dnsZone.ID == "" {
+			return nil, fmt.Errorf("invalid zone '%v', expected an ID", zone)
+		}
+		if hasSecret {
+			namespace, name, ok := strings.Cut(strings.TrimSpace(secret), "/")
+			if !ok || namespace == "" || name == "" {
+				return nil, fmt.Errorf("invalid credentials secret of zone '%v', expected namespace/name", zone)
+			}
+			dnsZone.CredentialsSecretRef = &v1.SecretReference{Namespace: namespace, Name: name}
+		}
+		parsed = append(parsed, dnsZone)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,48 @@
+package dnsrecord
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+func TestParseZones(t *testing.T) {
+	tests := []struct {
+		name      string
+		zones     string
+		expect    []v1.DNSZone
+		expectErr bool
+	}{
+		{
+			name:  "empty",
+			zones: "",
+		},
+		{
+			name:   "zones with and without credentials",
+			zones:  "Z1, Z2=test-namespace/account-2",
+			expect: []v1.DNSZone{{ID: "Z1"}, {ID: "Z2", CredentialsSecretRef: &v1.SecretReference{Namespace: "test-namespace", Name: "account-2"}}},
+		},
+		{
+			name:      "secret without namespace",
+			zones:     "Z1=account-1",
+			expectErr: true,
+		},
+		{
+			name:      "zone without ID",
+			zones:     "=test-namespace/account-1",
+			expectErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zones, err := ParseZones(tt.zones)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("expected error %v got %v", tt.expectErr, err)
+			}
+			if !reflect.DeepEqual(zones, tt.expect) {
+				t.Errorf("expected zones %v got %v", tt.expect, zones)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package domainverification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilclock "k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	trafficController "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/traffic"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+)
+
+const (
+	// DefaultCheckInterval is how often an unverified domain is checked again
+	DefaultCheckInterval = 30 * time.Second
+	// DefaultReverifyInterval is how often a verified domain is checked
+	// again, so a domain that changed hands loses its verification
+	DefaultReverifyInterval = time.Hour
+)
+
+// DomainVerificationReconciler reconciles a DomainVerification object
+type DomainVerificationReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// Resolver looks up the verification TXT records, dns.DefaultTXTResolver
+	// is used when nil
+	Resolver dns.TXTResolver
+	// CheckInterval is how often an unverified domain is checked again,
+	// DefaultCheckInterval is used when zero
+	CheckInterval time.Duration
+	// ReverifyInterval is how often a verified domain is checked again,
+	// DefaultReverifyInterval is used when zero
+	ReverifyInterval time.Duration
+	// OwnerID restricts the domains verified to the verifications labelled
+	// with it, those of other controllers sharing the control cluster are
+	// left alone
+	OwnerID string
+	// MaxConcurrentReconciles is the number of objects reconciled
+	// concurrently, one when zero
+	MaxConcurrentReconciles int
+}
+
+//+kubebuilder:rbac:groups=kuadrant.io,resources=domainverifications,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=kuadrant.io,resources=domainverifications/status,verbs=get;update;patch
+
+func (r *DomainVerificationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	_ = log.FromContext(ctx)
+
+	previous := &v1.DomainVerification{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: req.Name}, previous)
+	if err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if previous.Status.Verified && previous.Status.LastChecked != nil {
+		if next := previous.Status.LastChecked.Add(r.reverifyInterval()); clock.Now().Before(next) {
+			return ctrl.Result{RequeueAfter: next.Sub(clock.Now())}, nil
+		}
+	}
+	verification := previous.DeepCopy()
+
+	r.check(ctx, verification)
+	if !equality.Semantic.DeepEqual(previous.Status, verification.Status) {
+		if err := r.Status().Update(ctx, verification); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	switch {
+	case verification.Status.Verified && !previous.Status.Verified:
+		log.Log.Info("domain verified", "domain", verification.Spec.Domain, "owner", verification.Spec.Owner)
+	case !verification.Status.Verified && previous.Status.Verified:
+		log.Log.Info("domain verification lost", "domain", verification.Spec.Domain, "owner", verification.Spec.Owner, "reason", verification.Status.Message)
+	}
+	if verification.Status.Verified {
+		return ctrl.Result{RequeueAfter: r.reverifyInterval()}, nil
+	}
+	return ctrl.Result{RequeueAfter: r.checkInterval()}, nil
+}
+
+// check looks up the TXT record of the domain and records the outcome in the
+// status of the verification. A verified domain stays verified when the
+// lookup fails, and loses its verification once the token is removed.
+func (r *DomainVerificationReconciler) check(ctx context.Context, verification *v1.DomainVerification) {
+	now := metav1.NewTime(clock.Now())
+	verification.Status.LastChecked = &now
+
+	records, err := r.resolver().LookupTXT(ctx, verification.RecordName())
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		// the record was removed
+		records, err = nil, nil
+	}
+	switch {
+	case err != nil:
+		verification.Status.Message = fmt.Sprintf("failed to look up TXT record %s: %v", verification.RecordName(), err)
+	case len(records) == 0:
+		verification.Status.Verified = false
+		verification.Status.Message = fmt.Sprintf("TXT record %s not found", verification.RecordName())
+	case !slice.ContainsString(records, verification.Spec.Token):
+		verification.Status.Verified = false
+		verification.Status.Message = fmt.Sprintf("TXT record %s does not contain the verification token", verification.RecordName())
+	default:
+		verification.Status.Verified = true
+		verification.Status.Message = fmt.Sprintf("TXT record %s contains the verification token", verification.RecordName())
+	}
+}
+
+func (r *DomainVerificationReconciler) resolver() dns.TXTResolver {
+	if r.Resolver == nil {
+		return dns.DefaultTXTResolver
+	}
+	return r.Resolver
+}
+
+func (r *DomainVerificationReconciler) checkInterval() time.Duration {
+	if r.CheckInterval == 0 {
+		return DefaultCheckInterval
+	}
+	return r.CheckInterval
+}
+
+func (r *DomainVerificationReconciler) reverifyInterval() time.Duration {
+	if r.ReverifyInterval == 0 {
+		return DefaultReverifyInterval
+	}
+	return r.ReverifyInterval
+}
+
+// clock is to enable unit testing
+var clock utilclock.Clock = utilclock.RealClock{}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DomainVerificationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.DomainVerification{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return trafficController.IsOwned(obj, r.OwnerID)
+		}))).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(r)
+}
@@ -0,0 +1,141 @@
+package domainverification
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilclock "k8s.io/utils/clock"
+	clocktesting "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	trafficController "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/traffic"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+)
+
+type failingResolver struct{}
+
+func (failingResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	return nil, errors.New("server misbehaving")
+}
+
+func TestDomainVerificationReconciler_Reconcile(t *testing.T) {
+	// the status is stored with a precision of seconds
+	fakeClock := clocktesting.NewFakeClock(time.Now().Truncate(time.Second))
+	clock = fakeClock
+	defer func() { clock = utilclock.RealClock{} }()
+	checkedRecently := metav1.NewTime(fakeClock.Now().Add(-time.Minute))
+	checkedLongAgo := metav1.NewTime(fakeClock.Now().Add(-2 * DefaultReverifyInterval))
+
+	tests := []struct {
+		name           string
+		resolver       dns.TXTResolver
+		status         v1.DomainVerificationStatus
+		expectVerified bool
+		expectChecked  bool
+		expectRequeue  time.Duration
+	}{
+		{
+			name:          "no TXT record",
+			resolver:      dns.FakeTXTResolver{},
+			expectChecked: true,
+			expectRequeue: DefaultCheckInterval,
+		},
+		{
+			name:          "TXT record without the token",
+			resolver:      dns.FakeTXTResolver{"_mctc-verify.app.team.com": {"other-token"}},
+			expectChecked: true,
+			expectRequeue: DefaultCheckInterval,
+		},
+		{
+			name:           "TXT record with the token",
+			resolver:       dns.FakeTXTResolver{"_mctc-verify.app.team.com": {"other-token", "test-token"}},
+			expectVerified: true,
+			expectChecked:  true,
+			expectRequeue:  DefaultReverifyInterval,
+		},
+		{
+			name:           "verified recently",
+			resolver:       dns.FakeTXTResolver{},
+			status:         v1.DomainVerificationStatus{Verified: true, LastChecked: &checkedRecently},
+			expectVerified: true,
+			expectRequeue:  DefaultReverifyInterval - time.Minute,
+		},
+		{
+			name:          "token removed since the verification",
+			resolver:      dns.FakeTXTResolver{"_mctc-verify.app.team.com": {"new-owner-token"}},
+			status:        v1.DomainVerificationStatus{Verified: true, LastChecked: &checkedLongAgo},
+			expectChecked: true,
+			expectRequeue: DefaultCheckInterval,
+		},
+		{
+			name:          "record removed since the verification",
+			resolver:      dns.FakeTXTResolver{},
+			status:        v1.DomainVerificationStatus{Verified: true, LastChecked: &checkedLongAgo},
+			expectChecked: true,
+			expectRequeue: DefaultCheckInterval,
+		},
+		{
+			name:           "lookup failure keeps the verification",
+			resolver:       failingResolver{},
+			status:         v1.DomainVerificationStatus{Verified: true, LastChecked: &checkedLongAgo},
+			expectVerified: true,
+			expectChecked:  true,
+			expectRequeue:  DefaultReverifyInterval,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := v1.AddToScheme(scheme); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			verification := &v1.DomainVerification{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-verification",
+					Namespace: "test-control",
+					Labels:    map[string]string{trafficController.VerificationOwnerLabel: "test-owner"},
+				},
+				Spec: v1.DomainVerificationSpec{
+					Domain: "app.team.com",
+					Owner:  "cluster-a/Ingress/test-namespace/test-ingress",
+					Token:  "test-token",
+				},
+				Status: tt.status,
+			}
+			r := &DomainVerificationReconciler{
+				Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(verification).Build(),
+				Resolver: tt.resolver,
+			}
+
+			key := client.ObjectKeyFromObject(verification)
+			result, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := r.Get(context.TODO(), key, verification); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if verification.Status.Verified != tt.expectVerified {
+				t.Errorf("expected verified '%v' got '%v'", tt.expectVerified, verification.Status.Verified)
+			}
+			checked := verification.Status.LastChecked != nil && verification.Status.LastChecked.Time.Equal(fakeClock.Now())
+			if checked != tt.expectChecked {
+				t.Errorf("expected checked '%v' got '%v'", tt.expectChecked, verification.Status)
+			}
+			if tt.expectChecked && verification.Status.Message == "" {
+				t.Errorf("expected the check to be recorded in the status, got: %v", verification.Status)
+			}
+			if result.RequeueAfter != tt.expectRequeue {
+				t.Errorf("expected requeue after '%v' got '%v'", tt.expectRequeue, result.RequeueAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/multiClusterWatch"
+)
+
+const (
+	ARGO_CLUSTER_LABEL       = "argocd.argoproj.io/secret-type"
+	ARGO_CLUSTER_LABEL_VALUE = "cluster"
+	// DefaultArgoSelector selects the Argo CD cluster secrets
+	DefaultArgoSelector = ARGO_CLUSTER_LABEL + "=" + ARGO_CLUSTER_LABEL_VALUE
+
+	// ArgoInClusterServer is the server of the cluster Argo CD runs in, which
+	// is connected to with the in-cluster config when no credentials are set
+	ArgoInClusterServer = "https://kubernetes.default.svc"
+	// ArgoAWSAuthCommand is the exec plugin Argo CD authenticates to EKS
+	// clusters with, it must be on the path of the manager
+	ArgoAWSAuthCommand = "argocd-k8s-auth"
+	// ArgoAWSAuthAPIVersion is the client authentication API version of the
+	// credentials returned by ArgoAWSAuthCommand
+	ArgoAWSAuthAPIVersion = "client.authentication.k8s.io/v1beta1"
+)
+
+type TLSClientConfig struct {
+	Insecure   bool   `json:"insecure"`
+	ServerName string `json:"serverName,omitempty"`
+	CaData     []byte `json:"caData,omitempty"`
+	CertData   []byte `json:"certData,omitempty"`
+	KeyData    []byte `json:"keyData,omitempty"`
+}
+
+type ProviderConfig struct {
+	Command     string            `json:"command,omitempty"`
+	Args        []string          `json:"args,omitempty"`
+	Env         map[string]string `json:"env,omitempty"`
+	APIVersion  string            `json:"apiVersion,omitempty"`
+	InstallHint string            `json:"installHint,omitempty"`
+}
+
+type AWSAuthConfig struct {
+	ClusterName string `json:"clusterName,omitempty"`
+	RoleARN     string `json:"roleARN,omitempty"`
+}
+
+type ArgoClusterConfig struct {
+	BearerToken        string          `json:"bearerToken,omitempty"`
+	Username           string          `json:"username,omitempty"`
+	Password           string          `json:"password,omitempty"`
+	TlsClientConfig    TLSClientConfig `json:"tlsClientConfig,omitempty"`
+	AWSAuthConfig      *AWSAuthConfig  `json:"awsAuthConfig,omitempty"`
+	ExecProviderConfig *ProviderConfig `json:"execProviderConfig,omitempty"`
+}
+
+// ArgoSource reads clusters from Argo CD cluster secrets
+type ArgoSource struct {
+	LabelSelector labels.Selector
+}
+
+var _ ClusterSource = &ArgoSource{}
+
+func (s *ArgoSource) Name() string {
+	return "argo"
+}
+
+func (s *ArgoSource) Object() client.Object {
+	return &corev1.Secret{}
+}
+
+func (s *ArgoSource) Selector() labels.Selector {
+	return s.LabelSelector
+}
+
+// Cluster returns the config of the cluster of the Argo CD cluster secret, its
+// friendly name defaults to the name of the cluster in the secret.
+func (s *ArgoSource) Cluster(_ context.Context, _ client.Client, obj client.Object) (*rest.Config, multiClusterWatch.ClusterAttributes, error) {
+	secret := obj.(*corev1.Secret)
+	restConfig, err := ArgoRESTConfig(secret)
+	if err != nil {
+		return nil, multiClusterWatch.ClusterAttributes{}, err
+	}
+	attributes, err := multiClusterWatch.ClusterAttributesFor(secret)
+	if err != nil {
+		return nil, multiClusterWatch.ClusterAttributes{}, err
+	}
+	if attributes.Name == "" {
+		attributes.Name = string(secret.Data["name"])
+	}
+	return restConfig, attributes, nil
+}
+
+// inClusterConfig is replaced in tests
+var inClusterConfig = rest.InClusterConfig
+
+// ArgoRESTConfig returns the config of the cluster of an Argo CD cluster
+// secret, interpreting the secret the way Argo CD does: the in-cluster config
+// is used for the in-cluster server without credentials, otherwise AWS auth
+// takes precedence over an exec provider, which takes precedence over the
+// basic auth and bearer token credentials.
+func ArgoRESTConfig(secret *corev1.Secret) (*rest.Config, error) {
+	server := string(secret.Data["server"])
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+	if serverURL.Scheme == "" || serverURL.Host == "" {
+		return nil, fmt.Errorf("invalid server '%v' in cluster secret %v/%v", server, secret.Namespace, secret.Name)
+	}
+
+	clusterConfig := &ArgoClusterConfig{}
+	if len(secret.Data["config"]) > 0 {
+		if err := json.Unmarshal(secret.Data["config"], clusterConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	tlsClientConfig := rest.TLSClientConfig{
+		Insecure:   clusterConfig.TlsClientConfig.Insecure,
+		ServerName: clusterConfig.TlsClientConfig.ServerName,
+		CertData:   clusterConfig.TlsClientConfig.CertData,
+		KeyData:    clusterConfig.TlsClientConfig.KeyData,
+		CAData:     clusterConfig.TlsClientConfig.CaData,
+	}
+
+	switch {
+	case server == ArgoInClusterServer && clusterConfig.Username == "" && clusterConfig.Password == "" && clusterConfig.BearerToken == "":
+		return inClusterConfig()
+	case clusterConfig.AWSAuthConfig != nil:
+		args := []string{"aws", "--cluster-name", clusterConfig.AWSAuthConfig.ClusterName}
+		if clusterConfig.AWSAuthConfig.RoleARN != "" {
+			args = append(args, "--role-arn", clusterConfig.AWSAuthConfig.RoleARN)
+		}
+		return &rest.Config{
+			Host:            server,
+			TLSClientConfig: tlsClientConfig,
+			ExecProvider: &clientcmdapi.ExecConfig{
+				APIVersion:      ArgoAWSAuthAPIVersion,
+				Command:         ArgoAWSAuthCommand,
+				Args:            args,
+				InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+			},
+		}, nil
+	case clusterConfig.ExecProviderConfig != nil:
+		var env []clientcmdapi.ExecEnvVar
+		for name, value := range clusterConfig.ExecProviderConfig.Env {
+			env = append(env, clientcmdapi.ExecEnvVar{Name: name, Value: value})
+		}
+		sort.Slice(env, func(i, j int) bool { return env[i].Name < env[j].Name })
+		return &rest.Config{
+			Host:            server,
+			TLSClientConfig: tlsClientConfig,
+			ExecProvider: &clientcmdapi.ExecConfig{
+				APIVersion:      clusterConfig.ExecProviderConfig.APIVersion,
+				Command:         clusterConfig.ExecProviderConfig.Command,
+				Args:            clusterConfig.ExecProviderConfig.Args,
+				Env:             env,
+				InstallHint:     clusterConfig.ExecProviderConfig.InstallHint,
+				InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+			},
+		}, nil
+	default:
+		return &rest.Config{
+			Host:            server,
+			Username:        clusterConfig.Username,
+			Password:        clusterConfig.Password,
+			BearerToken:     clusterConfig.BearerToken,
+			TLSClientConfig: tlsClientConfig,
+		}, nil
+	}
+}
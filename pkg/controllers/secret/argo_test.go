@@ -0,0 +1,143 @@
+package secret
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestArgoRESTConfig(t *testing.T) {
+	inCluster := &rest.Config{Host: "https://10.0.0.1:443", BearerToken: "in-cluster-token"}
+	inClusterConfig = func() (*rest.Config, error) { return inCluster, nil }
+	defer func() { inClusterConfig = rest.InClusterConfig }()
+
+	tests := []struct {
+		name      string
+		server    string
+		config    string
+		expected  *rest.Config
+		expectErr bool
+	}{
+		{
+			name:   "bearer token with insecure TLS",
+			server: "https://127.0.0.1:64094",
+			config: `{"bearerToken": "token", "tlsClientConfig": {"insecure": true}}`,
+			expected: &rest.Config{
+				Host:            "https://127.0.0.1:64094",
+				BearerToken:     "token",
+				TLSClientConfig: rest.TLSClientConfig{Insecure: true},
+			},
+		},
+		{
+			name:   "basic auth with client certificates",
+			server: "https://cluster.example.com",
+			config: `{"username": "admin", "password": "secret", "tlsClientConfig": {"serverName": "api.example.com", "caData": "Y2E=", "certData": "Y2VydA==", "keyData": "a2V5"}}`,
+			expected: &rest.Config{
+				Host:     "https://cluster.example.com",
+				Username: "admin",
+				Password: "secret",
+				TLSClientConfig: rest.TLSClientConfig{
+					ServerName: "api.example.com",
+					CAData:     []byte("ca"),
+					CertData:   []byte("cert"),
+					KeyData:    []byte("key"),
+				},
+			},
+		},
+		{
+			name:   "server with path prefix",
+			server: "https://rancher.example.com/k8s/clusters/c-m-abcdef",
+			config: `{"bearerToken": "token"}`,
+			expected: &rest.Config{
+				Host:        "https://rancher.example.com/k8s/clusters/c-m-abcdef",
+				BearerToken: "token",
+			},
+		},
+		{
+			name:   "exec provider",
+			server: "https://cluster.example.com",
+			config: `{"execProviderConfig": {"command": "gke-gcloud-auth-plugin", "args": ["--verbose"], "env": {"B": "2", "A": "1"}, "apiVersion": "client.authentication.k8s.io/v1beta1", "installHint": "install it"}, "bearerToken": "ignored"}`,
+			expected: &rest.Config{
+				Host: "https://cluster.example.com",
+				ExecProvider: &clientcmdapi.ExecConfig{
+					Command:         "gke-gcloud-auth-plugin",
+					Args:            []string{"--verbose"},
+					Env:             []clientcmdapi.ExecEnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}},
+					APIVersion:      "client.authentication.k8s.io/v1beta1",
+					InstallHint:     "install it",
+					InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+				},
+			},
+		},
+		{
+			name:   "AWS auth takes precedence over the exec provider",
+			server: "https://eks.example.com",
+			config: `{"awsAuthConfig": {"clusterName": "prod", "roleARN": "arn:aws:iam::123456789012:role/argocd"}, "execProviderConfig": {"command": "ignored"}, "tlsClientConfig": {"caData": "Y2E="}}`,
+			expected: &rest.Config{
+				Host:            "https://eks.example.com",
+				TLSClientConfig: rest.TLSClientConfig{CAData: []byte("ca")},
+				ExecProvider: &clientcmdapi.ExecConfig{
+					Command:         ArgoAWSAuthCommand,
+					Args:            []string{"aws", "--cluster-name", "prod", "--role-arn", "arn:aws:iam::123456789012:role/argocd"},
+					APIVersion:      ArgoAWSAuthAPIVersion,
+					InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+				},
+			},
+		},
+		{
+			name:     "in-cluster server without credentials",
+			server:   ArgoInClusterServer,
+			config:   `{"tlsClientConfig": {"insecure": false}}`,
+			expected: inCluster,
+		},
+		{
+			name:   "in-cluster server with credentials",
+			server: ArgoInClusterServer,
+			config: `{"bearerToken": "token"}`,
+			expected: &rest.Config{
+				Host:        ArgoInClusterServer,
+				BearerToken: "token",
+			},
+		},
+		{
+			name:      "server without scheme",
+			server:    "127.0.0.1:64094",
+			config:    `{}`,
+			expectErr: true,
+		},
+		{
+			name:      "invalid config",
+			server:    "https://cluster.example.com",
+			config:    `{"bearerToken": `,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "cluster",
+					Namespace: "argocd",
+					Labels:    map[string]string{ARGO_CLUSTER_LABEL: ARGO_CLUSTER_LABEL_VALUE},
+				},
+				Data: map[string][]byte{
+					"name":   []byte("cluster"),
+					"server": []byte(tt.server),
+					"config": []byte(tt.config),
+				},
+			}
+			config, err := ArgoRESTConfig(secret)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("expected error %v got %v", tt.expectErr, err)
+			}
+			if !tt.expectErr && !reflect.DeepEqual(config, tt.expected) {
+				t.Errorf("expected config %+v got %+v", tt.expected, config)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/multiClusterWatch"
+)
+
+const (
+	KUBECONFIG_CLUSTER_LABEL       = "kuadrant.io/secret-type"
+	KUBECONFIG_CLUSTER_LABEL_VALUE = "kubeconfig"
+	// DefaultKubeconfigSelector selects the secrets holding the kubeconfig of
+	// a workload cluster
+	DefaultKubeconfigSelector = KUBECONFIG_CLUSTER_LABEL + "=" + KUBECONFIG_CLUSTER_LABEL_VALUE
+)
+
+// KubeconfigKeys are the keys the kubeconfig is read from, in order. The
+// second is the key of the kubeconfig secrets of Cluster API clusters.
+var KubeconfigKeys = []string{"kubeconfig", "value"}
+
+// KubeconfigSource reads clusters from secrets holding a kubeconfig, the
+// cluster of the current context of the kubeconfig is watched
+type KubeconfigSource struct {
+	LabelSelector labels.Selector
+}
+
+var _ ClusterSource = &KubeconfigSource{}
+
+func (s *KubeconfigSource) Name() string {
+	return "kubeconfig"
+}
+
+func (s *KubeconfigSource) Object() client.Object {
+	return &corev1.Secret{}
+}
+
+func (s *KubeconfigSource) Selector() labels.Selector {
+	return s.LabelSelector
+}
+
+// Cluster returns the config of the current context of the kubeconfig in the
+// secret, its friendly name defaults to the name of the secret.
+func (s *KubeconfigSource) Cluster(_ context.Context, _ client.Client, obj client.Object) (*rest.Config, multiClusterWatch.ClusterAttributes, error) {
+	secret := obj.(*corev1.Secret)
+	var kubeconfig []byte
+	for _, key := range KubeconfigKeys {
+		if kubeconfig = secret.Data[key]; len(kubeconfig) > 0 {
+			break
+		}
+	}
+	if len(kubeconfig) == 0 {
+		return nil, multiClusterWatch.ClusterAttributes{}, fmt.Errorf("no kubeconfig in secret %v/%v, expected one of the keys %v", secret.Namespace, secret.Name, KubeconfigKeys)
+	}
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, multiClusterWatch.ClusterAttributes{}, err
+	}
+	attributes, err := multiClusterWatch.ClusterAttributesFor(secret)
+	if err != nil {
+		return nil, multiClusterWatch.ClusterAttributes{}, err
+	}
+	if attributes.Name == "" {
+		attributes.Name = secret.Name
+	}
+	return restConfig, attributes, nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/multiClusterWatch"
+)
+
+const (
+	// DefaultOCMCredentialsSecret is the name of the secret holding the
+	// credentials of a managed cluster, in the namespace of the cluster. The
+	// secret of a ManagedServiceAccount of that name holds them in the
+	// expected keys.
+	DefaultOCMCredentialsSecret = "multi-cluster-traffic-controller"
+
+	OCMTokenKey = "token"
+	OCMCAKey    = "ca.crt"
+)
+
+var ManagedClusterGVK = schema.GroupVersionKind{
+	Group:   "cluster.open-cluster-management.io",
+	Version: "v1",
+	Kind:    "ManagedCluster",
+}
+
+//+kubebuilder:rbac:groups=cluster.open-cluster-management.io,resources=managedclusters,verbs=get;list;watch
+
+// OCMSource reads clusters from Open Cluster Management ManagedClusters. The
+// API server of a cluster is the first of its client configs and the
+// credentials are read from the credentials secret in the namespace of the
+// cluster.
+type OCMSource struct {
+	LabelSelector     labels.Selector
+	CredentialsSecret string
+}
+
+var _ CredentialsSource = &OCMSource{}
+
+func (s *OCMSource) Name() string {
+	return "ocm"
+}
+
+func (s *OCMSource) Object() client.Object {
+	managedCluster := &unstructured.Unstructured{}
+	managedCluster.SetGroupVersionKind(ManagedClusterGVK)
+	return managedCluster
+}
+
+func (s *OCMSource) Selector() labels.Selector {
+	return s.LabelSelector
+}
+
+func (s *OCMSource) Cluster(ctx context.Context, c client.Client, obj client.Object) (*rest.Config, multiClusterWatch.ClusterAttributes, error) {
+	managedCluster := obj.(*unstructured.Unstructured)
+	clientConfigs, _, err := unstructured.NestedSlice(managedCluster.Object, "spec", "managedClusterClientConfigs")
+	if err != nil {
+		return nil, multiClusterWatch.ClusterAttributes{}, err
+	}
+	if len(clientConfigs) == 0 {
+		return nil, multiClusterWatch.ClusterAttributes{}, fmt.Errorf("managed cluster %v has no client configs", managedCluster.GetName())
+	}
+	clientConfig, ok := clientConfigs[0].(map[string]interface{})
+	if !ok {
+		return nil, multiClusterWatch.ClusterAttributes{}, fmt.Errorf("invalid client config of managed cluster %v", managedCluster.GetName())
+	}
+	server, _, _ := unstructured.NestedString(clientConfig, "url")
+	if server == "" {
+		return nil, multiClusterWatch.ClusterAttributes{}, fmt.Errorf("managed cluster %v has no API server url", managedCluster.GetName())
+	}
+
+	credentials := &corev1.Secret{}
+	if err := c.Get(ctx, s.credentialsKey(managedCluster.GetName()), credentials); err != nil {
+		return nil, multiClusterWatch.ClusterAttributes{}, fmt.Errorf("failed to get credentials of managed cluster %v: %w", managedCluster.GetName(), err)
+	}
+	caData := credentials.Data[OCMCAKey]
+	if caBundle, _, _ := unstructured.NestedString(clientConfig, "caBundle"); caBundle != "" {
+		if caData, err = base64.StdEncoding.DecodeString(caBundle); err != nil {
+			return nil, multiClusterWatch.ClusterAttributes{}, fmt.Errorf("invalid CA bundle of managed cluster %v: %w", managedCluster.GetName(), err)
+		}
+	}
+	restConfig := &rest.Config{
+		Host:            server,
+		BearerToken:     string(credentials.Data[OCMTokenKey]),
+		TLSClientConfig: rest.TLSClientConfig{CAData: caData},
+	}
+
+	attributes, err := multiClusterWatch.ClusterAttributesFor(managedCluster)
+	if err != nil {
+		return nil, multiClusterWatch.ClusterAttributes{}, err
+	}
+	if attributes.Name == "" {
+		attributes.Name = managedCluster.GetName()
+	}
+	return restConfig, attributes, nil
+}
+
+// ClustersFor returns the managed cluster of the namespace of the secret when
+// it is a credentials secret.
+func (s *OCMSource) ClustersFor(secret *corev1.Secret) []reconcile.Request {
+	if secret.Name != s.credentialsSecret() {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: secret.Namespace}}}
+}
+
+func (s *OCMSource) credentialsKey(cluster string) client.ObjectKey {
+	return client.ObjectKey{Namespace: cluster, Name: s.credentialsSecret()}
+}
+
+func (s *OCMSource) credentialsSecret() string {
+	if s.CredentialsSecret == "" {
+		return DefaultOCMCredentialsSecret
+	}
+	return s.CredentialsSecret
+}
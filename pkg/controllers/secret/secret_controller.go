@@ -18,125 +18,134 @@ package secret
 
 import (
 	"context"
-	"net/url"
-	"strings"
+	"sync"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/util/json"
-	"k8s.io/client-go/rest"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
-	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/multiClusterWatch"
 )
 
-// SecretReconciler reconciles a Secret object
+const (
+	// The reasons of the events recorded on the objects registering clusters
+	WatchingReason         = "Watching"
+	InvalidClusterReason   = "InvalidCluster"
+	ConnectionFailedReason = "ConnectionFailed"
+)
+
+// SecretReconciler watches the workload clusters registered through the
+// objects of a cluster source
 type SecretReconciler struct {
 	client.Client
-	Scheme  *runtime.Scheme
-	MCWatch multiClusterWatch.Interface
-}
-
-type TLSClientConfig struct {
-	Insecure bool   `json:"insecure"`
-	CaData   []byte `json:"caData,omitempty"`
-	CertData []byte `json:"certData,omitempty"`
-	KeyData  []byte `json:"keyData,omitempty"`
-}
-
-type ProviderConfig struct {
-	Command    string   `json:"command,omitempty"`
-	Args       []string `json:"args,omitempty"`
-	APIVersion string   `json:"apiVersion,omitempty"`
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	MCWatch  multiClusterWatch.Interface
+	Source   ClusterSource
+	// MaxConcurrentReconciles is the number of objects reconciled
+	// concurrently, one when zero
+	MaxConcurrentReconciles int
+
+	lock sync.Mutex
+	// watching holds the cluster each object was last reported watching
+	watching map[types.NamespacedName]watchedCluster
 }
 
-type ArgoClusterConfig struct {
-	BearerToken        string          `json:"bearerToken,omitempty"`
-	Username           string          `json:"username,omitempty"`
-	Password           string          `json:"password,omitempty"`
-	TlsClientConfig    TLSClientConfig `json:"tlsClientConfig,omitempty"`
-	ExecProviderConfig ProviderConfig  `json:"execProviderConfig,omitempty"`
+// watchedCluster is the host and attributes of a watched cluster
+type watchedCluster struct {
+	host       string
+	attributes multiClusterWatch.ClusterAttributes
 }
 
-const (
-	ARGO_CLUSTER_LABEL       = "argocd.argoproj.io/secret-type"
-	ARGO_CLUSTER_LABEL_VALUE = "cluster"
-)
-
 //+kubebuilder:rbac:groups=,resources=secret,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=,resources=secret/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=,resources=secret/finalizers,verbs=update
 
-// Reconcile is part of the main kubernetes reconciliation loop which aims to
-// move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the Secret object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
-//
-// For more details, check Reconcile and its Result here:
-// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.13.1/pkg/reconcile
+// Reconcile watches the cluster registered by the object, or updates the
+// attributes of the cluster when it is already watched.
 func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	_ = log.FromContext(ctx)
-	previous := &corev1.Secret{}
-	err := r.Client.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: req.Name}, previous)
+	previous := r.Source.Object()
+	err := r.Client.Get(ctx, req.NamespacedName, previous)
 	if err != nil {
-		return ctrl.Result{}, err
+		if apierrors.IsNotFound(err) {
+			r.setWatching(req.NamespacedName, nil)
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
-	secret := previous.DeepCopy()
-
-	clusterClientConfig := &ArgoClusterConfig{}
-	err = json.Unmarshal(secret.Data["config"], clusterClientConfig)
-	if err != nil {
-		return ctrl.Result{}, err
+	obj := previous.DeepCopyObject().(client.Object)
+	if !r.Source.Selector().Matches(labels.Set(obj.GetLabels())) {
+		// requeued for a change of credentials after the object was
+		// deselected
+		return ctrl.Result{}, nil
 	}
 
-	hostUrl, err := url.Parse(string(secret.Data["server"]))
+	restConfig, attributes, err := r.Source.Cluster(ctx, r.Client, obj)
 	if err != nil {
+		log.Log.Error(err, "failed to read cluster", "source", r.Source.Name(), "name", req.String())
+		r.Recorder.Eventf(obj, corev1.EventTypeWarning, InvalidClusterReason, "Failed to read the cluster: %v", err)
 		return ctrl.Result{}, err
 	}
 
-	restConfig := &rest.Config{
-		Host:        hostUrl.Host,
-		Username:    clusterClientConfig.Username,
-		Password:    clusterClientConfig.Password,
-		BearerToken: clusterClientConfig.BearerToken,
-		TLSClientConfig: rest.TLSClientConfig{
-			ServerName: strings.SplitN(hostUrl.Host, ":", 2)[0],
-			CertData:   clusterClientConfig.TlsClientConfig.CertData,
-			KeyData:    clusterClientConfig.TlsClientConfig.KeyData,
-			CAData:     clusterClientConfig.TlsClientConfig.CaData,
-		},
-	}
-
-	_, err = r.MCWatch.WatchCluster(restConfig)
+	_, err = r.MCWatch.WatchCluster(restConfig, attributes)
 
 	if err != nil {
-		log.Log.Info("error occurred", "error", err)
+		log.Log.Error(err, "failed to watch cluster", "source", r.Source.Name(), "name", req.String(), "cluster", restConfig.Host)
+		r.Recorder.Eventf(obj, corev1.EventTypeWarning, ConnectionFailedReason, "Failed to connect to cluster %v: %v", restConfig.Host, err)
 		return ctrl.Result{}, err
 	}
+	if r.setWatching(req.NamespacedName, &watchedCluster{host: restConfig.Host, attributes: attributes}) {
+		r.Recorder.Eventf(obj, corev1.EventTypeNormal, WatchingReason, "Watching cluster %v", restConfig.Host)
+	}
 	return ctrl.Result{}, nil
 }
 
+// setWatching records the cluster watched for the object, or forgets the
+// object when the cluster is nil. Returns whether the cluster differs from the
+// one last recorded, so the cluster is reported once rather than on every
+// reconcile.
+func (r *SecretReconciler) setWatching(key types.NamespacedName, cluster *watchedCluster) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if cluster == nil {
+		delete(r.watching, key)
+		return false
+	}
+	if previous, ok := r.watching[key]; ok && previous.host == cluster.host && equality.Semantic.DeepEqual(previous.attributes, cluster.attributes) {
+		return false
+	}
+	if r.watching == nil {
+		r.watching = map[types.NamespacedName]watchedCluster{}
+	}
+	r.watching[key] = *cluster
+	return true
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *SecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.Secret{}).
-		WithEventFilter(predicate.Funcs{
-			CreateFunc: func(e event.CreateEvent) bool {
-				return metadata.HasLabel(e.Object, ARGO_CLUSTER_LABEL) && e.Object.GetLabels()[ARGO_CLUSTER_LABEL] == ARGO_CLUSTER_LABEL_VALUE
-			},
-			DeleteFunc: func(e event.DeleteEvent) bool {
-				return metadata.HasLabel(e.Object, ARGO_CLUSTER_LABEL) && e.Object.GetLabels()[ARGO_CLUSTER_LABEL] == ARGO_CLUSTER_LABEL_VALUE
-			},
-			UpdateFunc: func(e event.UpdateEvent) bool {
-				return metadata.HasLabel(e.ObjectNew, ARGO_CLUSTER_LABEL) && e.ObjectNew.GetLabels()[ARGO_CLUSTER_LABEL] == ARGO_CLUSTER_LABEL_VALUE
-			},
-		}).
-		Complete(r)
+	selected := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return r.Source.Selector().Matches(labels.Set(obj.GetLabels()))
+	})
+	b := ctrl.NewControllerManagedBy(mgr).
+		Named(r.Source.Name()+"-cluster-source").
+		For(r.Source.Object(), builder.WithPredicates(selected))
+	if credentials, ok := r.Source.(CredentialsSource); ok {
+		b = b.Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+			return credentials.ClustersFor(obj.(*corev1.Secret))
+		}))
+	}
+	return b.WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).Complete(r)
 }
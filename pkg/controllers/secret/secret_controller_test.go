@@ -0,0 +1,92 @@
+package secret
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/multiClusterWatch"
+)
+
+type testWatch struct {
+	watched []string
+}
+
+func (w *testWatch) WatchCluster(config *rest.Config, _ multiClusterWatch.ClusterAttributes) (multiClusterWatch.Watcher, error) {
+	w.watched = append(w.watched, config.Host)
+	return nil, nil
+}
+
+func TestSecretReconcilerEvents(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "workload-kubeconfig",
+			Namespace: "clusters",
+			Labels:    map[string]string{KUBECONFIG_CLUSTER_LABEL: KUBECONFIG_CLUSTER_LABEL_VALUE},
+		},
+		Data: map[string][]byte{"kubeconfig": []byte(testKubeconfig)},
+	}
+	c := fake.NewClientBuilder().WithObjects(secret).Build()
+	recorder := record.NewFakeRecorder(10)
+	watch := &testWatch{}
+	r := &SecretReconciler{
+		Client:   c,
+		Recorder: recorder,
+		MCWatch:  watch,
+		Source:   &KubeconfigSource{LabelSelector: labels.SelectorFromSet(secret.Labels)},
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: secret.Namespace, Name: secret.Name}}
+
+	expectEvents := func(expected ...string) {
+		t.Helper()
+		for _, prefix := range expected {
+			select {
+			case event := <-recorder.Events:
+				if !strings.HasPrefix(event, prefix) {
+					t.Errorf("expected event '%v' got '%v'", prefix, event)
+				}
+			default:
+				t.Errorf("expected event '%v'", prefix)
+			}
+		}
+		select {
+		case event := <-recorder.Events:
+			t.Errorf("expected no more events got '%v'", event)
+		default:
+		}
+	}
+
+	// the cluster is reported when it is first watched
+	if _, err := r.Reconcile(context.TODO(), req); err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+	expectEvents("Normal " + WatchingReason)
+
+	// reconciling the same cluster again is not reported again
+	if _, err := r.Reconcile(context.TODO(), req); err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+	expectEvents()
+	if len(watch.watched) != 2 {
+		t.Errorf("expected the cluster to be watched on every reconcile got %v", watch.watched)
+	}
+
+	// a change of the attributes of the cluster is reported
+	secret.Annotations = map[string]string{"kuadrant.io/cluster-name": "cluster-a"}
+	if err := c.Update(context.TODO(), secret); err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+	if _, err := r.Reconcile(context.TODO(), req); err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+	expectEvents("Normal " + WatchingReason)
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/multiClusterWatch"
+)
+
+// ClusterSource reads the workload clusters registered through the objects of
+// a kind on the control cluster
+type ClusterSource interface {
+	// Name identifies the source in the name of its controller and in logs
+	Name() string
+	// Object returns an empty object of the kind clusters are registered with
+	Object() client.Object
+	// Selector selects the objects that register clusters
+	Selector() labels.Selector
+	// Cluster returns the config and attributes of the cluster the object
+	// registers
+	Cluster(ctx context.Context, c client.Client, obj client.Object) (*rest.Config, multiClusterWatch.ClusterAttributes, error)
+}
+
+// CredentialsSource is implemented by cluster sources that read the
+// credentials of a cluster from a secret other than the registering object, so
+// the cluster is reconciled again when its credentials change
+type CredentialsSource interface {
+	ClusterSource
+	// ClustersFor returns the objects registering the clusters whose
+	// credentials are held in the secret
+	ClustersFor(secret *corev1.Secret) []reconcile.Request
+}
+
+// ParseSelector parses the label selector of a cluster source
+func ParseSelector(source, selector string) (labels.Selector, error) {
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector for cluster source %v: %w", source, err)
+	}
+	return parsed, nil
+}
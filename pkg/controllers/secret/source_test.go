@@ -0,0 +1,181 @@
+package secret
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: workload
+contexts:
+- name: workload
+  context:
+    cluster: workload
+    user: admin
+- name: other
+  context:
+    cluster: other
+    user: admin
+clusters:
+- name: workload
+  cluster:
+    server: https://workload.example.com:6443
+- name: other
+  cluster:
+    server: https://other.example.com:6443
+users:
+- name: admin
+  user:
+    token: admin-token
+`
+
+func TestKubeconfigSource(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        map[string][]byte
+		expectHost  string
+		expectName  string
+		expectErr   bool
+		clusterName string
+	}{
+		{
+			name:       "kubeconfig key",
+			data:       map[string][]byte{"kubeconfig": []byte(testKubeconfig)},
+			expectHost: "https://workload.example.com:6443",
+			expectName: "workload-kubeconfig",
+		},
+		{
+			name:        "Cluster API value key",
+			data:        map[string][]byte{"value": []byte(testKubeconfig)},
+			expectHost:  "https://workload.example.com:6443",
+			expectName:  "cluster-a",
+			clusterName: "cluster-a",
+		},
+		{
+			name:      "no kubeconfig",
+			data:      map[string][]byte{"config": []byte(testKubeconfig)},
+			expectErr: true,
+		},
+		{
+			name:      "invalid kubeconfig",
+			data:      map[string][]byte{"kubeconfig": []byte("not: [a kubeconfig")},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "workload-kubeconfig", Namespace: "clusters"},
+				Data:       tt.data,
+			}
+			if tt.clusterName != "" {
+				secret.Annotations = map[string]string{"kuadrant.io/cluster-name": tt.clusterName}
+			}
+			config, attributes, err := (&KubeconfigSource{}).Cluster(context.TODO(), nil, secret)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("expected error %v got %v", tt.expectErr, err)
+			}
+			if tt.expectErr {
+				return
+			}
+			if config.Host != tt.expectHost {
+				t.Errorf("expected host '%v' got '%v'", tt.expectHost, config.Host)
+			}
+			if config.BearerToken != "admin-token" {
+				t.Errorf("expected the token of the current context got '%v'", config.BearerToken)
+			}
+			if attributes.Name != tt.expectName {
+				t.Errorf("expected name '%v' got '%v'", tt.expectName, attributes.Name)
+			}
+		})
+	}
+}
+
+func TestOCMSource(t *testing.T) {
+	credentials := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: DefaultOCMCredentialsSecret, Namespace: "cluster-a"},
+		Data: map[string][]byte{
+			OCMTokenKey: []byte("managed-token"),
+			OCMCAKey:    []byte("secret-ca"),
+		},
+	}
+	c := fake.NewClientBuilder().WithObjects(credentials).Build()
+	source := &OCMSource{}
+
+	managedCluster := func(name string, clientConfigs ...interface{}) *unstructured.Unstructured {
+		u := source.Object().(*unstructured.Unstructured)
+		u.SetName(name)
+		u.SetLabels(map[string]string{"kuadrant.io/continent": "EU"})
+		u.Object["spec"] = map[string]interface{}{"managedClusterClientConfigs": clientConfigs}
+		return u
+	}
+
+	tests := []struct {
+		name      string
+		cluster   *unstructured.Unstructured
+		expectCA  string
+		expectErr bool
+	}{
+		{
+			name:     "CA from the credentials secret",
+			cluster:  managedCluster("cluster-a", map[string]interface{}{"url": "https://cluster-a.example.com:6443"}),
+			expectCA: "secret-ca",
+		},
+		{
+			name: "CA bundle of the client config",
+			cluster: managedCluster("cluster-a", map[string]interface{}{
+				"url":      "https://cluster-a.example.com:6443",
+				"caBundle": base64.StdEncoding.EncodeToString([]byte("bundle-ca")),
+			}),
+			expectCA: "bundle-ca",
+		},
+		{
+			name:      "no client configs",
+			cluster:   managedCluster("cluster-a"),
+			expectErr: true,
+		},
+		{
+			name:      "no credentials",
+			cluster:   managedCluster("cluster-b", map[string]interface{}{"url": "https://cluster-b.example.com:6443"}),
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, attributes, err := source.Cluster(context.TODO(), c, tt.cluster)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("expected error %v got %v", tt.expectErr, err)
+			}
+			if tt.expectErr {
+				return
+			}
+			if config.Host != "https://cluster-a.example.com:6443" || config.BearerToken != "managed-token" {
+				t.Errorf("expected the server and token of the managed cluster got '%v' '%v'", config.Host, config.BearerToken)
+			}
+			if string(config.CAData) != tt.expectCA {
+				t.Errorf("expected CA '%v' got '%v'", tt.expectCA, string(config.CAData))
+			}
+			if attributes.Name != "cluster-a" || attributes.Continent != "EU" {
+				t.Errorf("expected the attributes of the managed cluster got %+v", attributes)
+			}
+		})
+	}
+
+	requests := source.ClustersFor(credentials)
+	if len(requests) != 1 || requests[0].NamespacedName != (types.NamespacedName{Name: "cluster-a"}) {
+		t.Errorf("expected the managed cluster of the credentials got %v", requests)
+	}
+	if requests := source.ClustersFor(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "cluster-a"}}); len(requests) != 0 {
+		t.Errorf("expected no managed clusters for other secrets got %v", requests)
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretsync
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	clusterLabel   = "cluster"
+	namespaceLabel = "namespace"
+	secretLabel    = "secret"
+)
+
+var (
+	// copySynced is a prometheus metric which is 1 when the copy of a TLS
+	// secret on a workload cluster has the data of the source secret and 0
+	// otherwise.
+	copySynced = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mctc_tls_secret_copy_synced",
+			Help: "MCTC whether the copy of a TLS secret on a workload cluster is in sync with the source",
+		},
+		[]string{clusterLabel, namespaceLabel, secretLabel},
+	)
+
+	// copyExpiry is a prometheus metric which holds the expiry time of the
+	// certificate in the copy of a TLS secret on a workload cluster.
+	copyExpiry = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mctc_tls_secret_copy_expiry_timestamp_seconds",
+			Help: "MCTC expiry time of the certificate in the copy of a TLS secret on a workload cluster",
+		},
+		[]string{clusterLabel, namespaceLabel, secretLabel},
+	)
+)
+
+func init() {
+	// Register metrics into the global prometheus registry
+	metrics.Registry.MustRegister(
+		copySynced,
+		copyExpiry,
+	)
+}
@@ -0,0 +1,349 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretsync
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	trafficController "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/traffic"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+const (
+	// SyncStatusAnnotation records the sync state of every copy of a source
+	// TLS secret on the workload clusters
+	SyncStatusAnnotation = "kuadrant.io/secret-sync-status"
+
+	// SyncedConditionType is true when a copy has the data of the source
+	SyncedConditionType = "Synced"
+
+	// CopyOutOfDateReason is the reason of the event recorded on the source
+	// secret when a copy could not be brought up to date
+	CopyOutOfDateReason = "CopyOutOfDate"
+
+	DefaultResyncPeriod = 5 * time.Minute
+)
+
+// WorkloadClusters provides clients for the watched workload clusters
+type WorkloadClusters interface {
+	// WorkloadClients returns a client for every workload cluster keyed by
+	// the cluster name
+	WorkloadClients() map[string]client.Client
+}
+
+// CopyStatus is the sync state of a copy of a source TLS secret
+type CopyStatus struct {
+	Cluster    string             `json:"cluster"`
+	Namespace  string             `json:"namespace"`
+	NotAfter   *metav1.Time       `json:"notAfter,omitempty"`
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// SecretSyncReconciler propagates renewals of the TLS secrets issued on the
+// control cluster to their copies on the workload clusters. The copies are
+// looked up in the namespaces of the traffic objects publishing the host of
+// the certificate, within the watch scope of each cluster, so no
+// cluster-wide access to secrets is needed.
+type SecretSyncReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	Clusters WorkloadClusters
+	// Namespace the source TLS secrets are issued in
+	Namespace string
+	// OwnerID restricts the secrets synced to those labelled with it, the
+	// secrets of other controllers sharing the control cluster are left alone
+	OwnerID string
+	// ResyncPeriod is how often the copies are checked when the source does
+	// not change, DefaultResyncPeriod is used when zero
+	ResyncPeriod time.Duration
+	// MaxConcurrentReconciles is the number of objects reconciled
+	// concurrently, one when zero
+	MaxConcurrentReconciles int
+
+	metricsLock sync.Mutex
+	// metricCopies holds the copies metrics were recorded for by source
+	metricCopies map[types.NamespacedName][]CopyStatus
+}
+
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups=kuadrant.io,resources=dnsrecords,verbs=get;list;watch
+
+func (r *SecretSyncReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	_ = log.FromContext(ctx)
+
+	source := &corev1.Secret{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: req.Name}, source)
+	if k8serrors.IsNotFound(err) {
+		r.recordMetrics(req.NamespacedName, nil)
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	previous := map[string]CopyStatus{}
+	if value := metadata.GetAnnotation(source, SyncStatusAnnotation); value != "" {
+		var statuses []CopyStatus
+		if err := json.Unmarshal([]byte(value), &statuses); err != nil {
+			log.Log.Error(err, "ignoring invalid secret sync status", "secret", req.NamespacedName)
+		}
+		for _, status := range statuses {
+			previous[status.Cluster+"/"+status.Namespace] = status
+		}
+	}
+
+	locations, err := r.copyLocations(ctx, source)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	sourceNotAfter, _ := certificateNotAfter(source.Data[corev1.TLSCertKey])
+
+	clients := r.Clusters.WorkloadClients()
+	var statuses []CopyStatus
+	var errs []error
+	for _, location := range locations {
+		c, ok := clients[location.Cluster]
+		if !ok {
+			continue
+		}
+		status := previous[location.Cluster+"/"+location.Namespace]
+		status.Cluster = location.Cluster
+		status.Namespace = location.Namespace
+
+		target := &corev1.Secret{}
+		err := c.Get(ctx, client.ObjectKey{Namespace: location.Namespace, Name: source.Name}, target)
+		if k8serrors.IsNotFound(err) || errors.Is(err, traffic.ErrOutOfScope) {
+			// the secret has not been copied to the namespace yet, or the
+			// namespace is no longer watched
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get secret %s/%s on cluster %s: %w", location.Namespace, source.Name, location.Cluster, err))
+			// the copy is out of date if the source was renewed since the
+			// copy was last seen
+			if status.NotAfter == nil || status.NotAfter.Time.Before(sourceNotAfter) {
+				r.setOutOfDate(source, &status, fmt.Errorf("failed to get the copy: %w", err))
+			}
+			statuses = append(statuses, status)
+			continue
+		}
+		if target.Labels[trafficController.ManagedByLabel] != trafficController.ManagedByLabelValue {
+			continue
+		}
+		r.syncCopy(ctx, source, c, target, &status)
+		statuses = append(statuses, status)
+	}
+
+	r.recordMetrics(req.NamespacedName, statuses)
+	if err := r.updateSyncStatus(ctx, source, statuses); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return ctrl.Result{}, utilerrors.NewAggregate(errs)
+	}
+	return ctrl.Result{RequeueAfter: r.resyncPeriod()}, nil
+}
+
+// copyLocations returns the cluster and namespace of every copy of the
+// source secret, which are those of the traffic objects with an endpoint in
+// the DNSRecord for the host of the certificate.
+func (r *SecretSyncReconciler) copyLocations(ctx context.Context, source *corev1.Secret) ([]CopyStatus, error) {
+	host := strings.TrimSuffix(source.Name, trafficController.CertificateSecretSuffix)
+	record := &v1.DNSRecord{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: r.Namespace, Name: host}, record)
+	if err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+
+	seen := map[string]bool{}
+	var locations []CopyStatus
+	for _, endpoint := range record.Spec.Endpoints {
+		cluster := endpoint.Labels[trafficController.ClusterEndpointLabel]
+		_, namespace, _ := trafficController.EndpointOwner(endpoint)
+		if cluster == "" || namespace == "" || seen[cluster+"/"+namespace] {
+			continue
+		}
+		seen[cluster+"/"+namespace] = true
+		locations = append(locations, CopyStatus{Cluster: cluster, Namespace: namespace})
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		if locations[i].Cluster != locations[j].Cluster {
+			return locations[i].Cluster < locations[j].Cluster
+		}
+		return locations[i].Namespace < locations[j].Namespace
+	})
+	return locations, nil
+}
+
+// syncCopy updates the target copy with the data of the source and records
+// the outcome in the status of the copy.
+func (r *SecretSyncReconciler) syncCopy(ctx context.Context, source *corev1.Secret, c client.Client, target *corev1.Secret, status *CopyStatus) {
+	if !equality.Semantic.DeepEqual(target.Data, source.Data) {
+		updated := target.DeepCopy()
+		updated.Data = source.DeepCopy().Data
+		if err := c.Update(ctx, updated); err != nil {
+			r.setNotAfter(status, target)
+			r.setOutOfDate(source, status, fmt.Errorf("failed to update the copy: %w", err))
+			return
+		}
+		log.Log.Info("synced TLS secret copy", "secret", target.Name, "namespace", target.Namespace, "cluster", status.Cluster)
+		target = updated
+	}
+
+	r.setNotAfter(status, target)
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:    SyncedConditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "UpToDate",
+		Message: "The target has the data of the source secret",
+	})
+}
+
+// setOutOfDate records that the copy is older than the source and alerts on
+// it.
+func (r *SecretSyncReconciler) setOutOfDate(source *corev1.Secret, status *CopyStatus, err error) {
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:    SyncedConditionType,
+		Status:  metav1.ConditionFalse,
+		Reason:  CopyOutOfDateReason,
+		Message: err.Error(),
+	})
+	r.Recorder.Eventf(source, corev1.EventTypeWarning, CopyOutOfDateReason,
+		"The target in namespace %s on cluster %s is older than the source: %v", status.Namespace, status.Cluster, err)
+}
+
+func (r *SecretSyncReconciler) setNotAfter(status *CopyStatus, target *corev1.Secret) {
+	status.NotAfter = nil
+	if notAfter, err := certificateNotAfter(target.Data[corev1.TLSCertKey]); err == nil {
+		status.NotAfter = &metav1.Time{Time: notAfter}
+	}
+}
+
+// recordMetrics sets the metrics of the copies of the source secret, deleting
+// the series of copies that are no longer synced.
+func (r *SecretSyncReconciler) recordMetrics(source types.NamespacedName, statuses []CopyStatus) {
+	r.metricsLock.Lock()
+	defer r.metricsLock.Unlock()
+	if r.metricCopies == nil {
+		r.metricCopies = map[types.NamespacedName][]CopyStatus{}
+	}
+
+	current := map[string]bool{}
+	for _, status := range statuses {
+		current[status.Cluster+"/"+status.Namespace] = true
+		synced := 0.0
+		if meta.IsStatusConditionTrue(status.Conditions, SyncedConditionType) {
+			synced = 1
+		}
+		copySynced.WithLabelValues(status.Cluster, status.Namespace, source.Name).Set(synced)
+		if status.NotAfter != nil {
+			copyExpiry.WithLabelValues(status.Cluster, status.Namespace, source.Name).Set(float64(status.NotAfter.Unix()))
+		} else {
+			copyExpiry.DeleteLabelValues(status.Cluster, status.Namespace, source.Name)
+		}
+	}
+	for _, status := range r.metricCopies[source] {
+		if !current[status.Cluster+"/"+status.Namespace] {
+			copySynced.DeleteLabelValues(status.Cluster, status.Namespace, source.Name)
+			copyExpiry.DeleteLabelValues(status.Cluster, status.Namespace, source.Name)
+		}
+	}
+	if len(statuses) == 0 {
+		delete(r.metricCopies, source)
+	} else {
+		r.metricCopies[source] = statuses
+	}
+}
+
+// updateSyncStatus records the status of the copies on the source secret.
+func (r *SecretSyncReconciler) updateSyncStatus(ctx context.Context, source *corev1.Secret, statuses []CopyStatus) error {
+	updated := source.DeepCopy()
+	if len(statuses) == 0 {
+		metadata.RemoveAnnotation(updated, SyncStatusAnnotation)
+	} else {
+		value, err := json.Marshal(statuses)
+		if err != nil {
+			return err
+		}
+		metadata.AddAnnotation(updated, SyncStatusAnnotation, string(value))
+	}
+	if equality.Semantic.DeepEqual(source.Annotations, updated.Annotations) {
+		return nil
+	}
+	return r.Client.Patch(ctx, updated, client.MergeFrom(source))
+}
+
+func (r *SecretSyncReconciler) resyncPeriod() time.Duration {
+	if r.ResyncPeriod == 0 {
+		return DefaultResyncPeriod
+	}
+	return r.ResyncPeriod
+}
+
+// certificateNotAfter returns the expiry time of the first certificate in the
+// PEM encoded data.
+func certificateNotAfter(data []byte) (time.Time, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return time.Time{}, fmt.Errorf("no PEM encoded certificate found")
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return certificate.NotAfter, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SecretSyncReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("secretsync").
+		For(&corev1.Secret{}).
+		WithEventFilter(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return obj.GetNamespace() == r.Namespace && obj.GetLabels()[trafficController.ManagedByLabel] == trafficController.ManagedByLabelValue && trafficController.IsOwned(obj, r.OwnerID)
+		})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(r)
+}
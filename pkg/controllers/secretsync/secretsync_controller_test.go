@@ -0,0 +1,222 @@
+package secretsync
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	trafficController "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/traffic"
+)
+
+type testClusters map[string]client.Client
+
+func (c testClusters) WorkloadClients() map[string]client.Client {
+	return c
+}
+
+// failingClient fails every update
+type failingClient struct {
+	client.Client
+}
+
+func (c failingClient) Update(_ context.Context, _ client.Object, _ ...client.UpdateOption) error {
+	return errors.New("cluster unreachable")
+}
+
+// unreachableClient fails every get
+type unreachableClient struct {
+	client.Client
+}
+
+func (c unreachableClient) Get(_ context.Context, _ client.ObjectKey, _ client.Object, _ ...client.GetOption) error {
+	return errors.New("cluster unreachable")
+}
+
+func testRecord(clusters ...string) *v1.DNSRecord {
+	record := &v1.DNSRecord{ObjectMeta: metav1.ObjectMeta{Namespace: "test-control", Name: "test.example.com"}}
+	for _, cluster := range clusters {
+		record.Spec.Endpoints = append(record.Spec.Endpoints, &v1.Endpoint{
+			DNSName: "test.example.com",
+			Labels: v1.Labels{
+				trafficController.ClusterEndpointLabel: cluster,
+				trafficController.OwnerEndpointLabel:   "Ingress/test-namespace/test-ingress",
+			},
+		})
+	}
+	return record
+}
+
+func expectEvent(t *testing.T, recorder *record.FakeRecorder, cluster string) {
+	t.Helper()
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, CopyOutOfDateReason) || !strings.Contains(event, cluster) {
+			t.Errorf("unexpected event '%v'", event)
+		}
+	default:
+		t.Errorf("expected an event for the out of date copy on %s", cluster)
+	}
+}
+
+func testCertificate(t *testing.T, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: notAfter.Add(-time.Hour), NotAfter: notAfter}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func testSecret(namespace string, cert []byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test.example.com-tls",
+			Namespace: namespace,
+			Labels:    map[string]string{trafficController.ManagedByLabel: trafficController.ManagedByLabelValue},
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{corev1.TLSCertKey: cert},
+	}
+}
+
+func TestSecretSyncReconciler_Reconcile(t *testing.T) {
+	oldExpiry := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	newExpiry := time.Now().Add(90 * 24 * time.Hour).Truncate(time.Second)
+	oldCert := testCertificate(t, oldExpiry)
+	newCert := testCertificate(t, newExpiry)
+
+	source := testSecret("test-control", newCert)
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := v1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dnsRecord := testRecord("cluster-a", "cluster-b")
+	controlClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(source, dnsRecord).Build()
+	clusterA := fake.NewClientBuilder().WithObjects(testSecret("test-namespace", oldCert)).Build()
+	clusterB := fake.NewClientBuilder().WithObjects(testSecret("test-namespace", oldCert)).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &SecretSyncReconciler{
+		Client:    controlClient,
+		Recorder:  recorder,
+		Clusters:  testClusters{"cluster-a": clusterA, "cluster-b": failingClient{clusterB}},
+		Namespace: "test-control",
+	}
+
+	key := client.ObjectKeyFromObject(source)
+	result, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RequeueAfter != DefaultResyncPeriod {
+		t.Errorf("expected requeue after '%v' got '%v'", DefaultResyncPeriod, result.RequeueAfter)
+	}
+
+	// the renewal is propagated to the reachable cluster
+	synced := &corev1.Secret{}
+	if err := clusterA.Get(context.TODO(), client.ObjectKey{Namespace: "test-namespace", Name: source.Name}, synced); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(synced.Data[corev1.TLSCertKey]) != string(newCert) {
+		t.Errorf("expected the copy on cluster-a to have the renewed certificate")
+	}
+
+	// the state of every copy is recorded on the source
+	if err := controlClient.Get(context.TODO(), key, source); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var statuses []CopyStatus
+	if err := json.Unmarshal([]byte(source.Annotations[SyncStatusAnnotation]), &statuses); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("expected the status of 2 copies, got: %v", statuses)
+	}
+	expect := map[string]struct {
+		status   metav1.ConditionStatus
+		notAfter time.Time
+	}{
+		"cluster-a": {status: metav1.ConditionTrue, notAfter: newExpiry},
+		"cluster-b": {status: metav1.ConditionFalse, notAfter: oldExpiry},
+	}
+	for _, status := range statuses {
+		if len(status.Conditions) != 1 || status.Conditions[0].Status != expect[status.Cluster].status {
+			t.Errorf("expected %s to have synced condition '%v' got: %v", status.Cluster, expect[status.Cluster].status, status.Conditions)
+		}
+		if status.NotAfter == nil || !status.NotAfter.Time.Equal(expect[status.Cluster].notAfter) {
+			t.Errorf("expected %s to expire at '%v' got '%v'", status.Cluster, expect[status.Cluster].notAfter, status.NotAfter)
+		}
+	}
+
+	// the out of date copy is alerted on
+	expectEvent(t, recorder, "cluster-b")
+	if synced := testutil.ToFloat64(copySynced.WithLabelValues("cluster-b", "test-namespace", source.Name)); synced != 0 {
+		t.Errorf("expected the copy on cluster-b to be out of sync got '%v'", synced)
+	}
+
+	// the source is renewed while cluster-a is unreachable
+	renewedCert := testCertificate(t, newExpiry.Add(24*time.Hour))
+	source.Data[corev1.TLSCertKey] = renewedCert
+	if err := controlClient.Update(context.TODO(), source); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r.Clusters = testClusters{"cluster-a": unreachableClient{clusterA}, "cluster-b": failingClient{clusterB}}
+	if _, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key}); err == nil {
+		t.Fatalf("expected an error for the unreachable cluster")
+	}
+	expectEvent(t, recorder, "cluster-a")
+	expectEvent(t, recorder, "cluster-b")
+
+	// the copy on cluster-b is no longer published
+	if err := controlClient.Get(context.TODO(), client.ObjectKeyFromObject(dnsRecord), dnsRecord); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dnsRecord.Spec.Endpoints = testRecord("cluster-a").Spec.Endpoints
+	if err := controlClient.Update(context.TODO(), dnsRecord); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r.Clusters = testClusters{"cluster-a": clusterA, "cluster-b": failingClient{clusterB}}
+	if _, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count := testutil.CollectAndCount(copySynced); count != 1 {
+		t.Errorf("expected the series of the copy on cluster-b to be deleted, got %v series", count)
+	}
+
+	// the source is deleted
+	if err := controlClient.Delete(context.TODO(), source); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count := testutil.CollectAndCount(copySynced) + testutil.CollectAndCount(copyExpiry); count != 0 {
+		t.Errorf("expected the series of the deleted source to be deleted, got %v series", count)
+	}
+}
@@ -0,0 +1,319 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package traffic
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	dnsAWS "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns/aws"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+const (
+	// ManagedByLabel marks the DNSRecords created from workload cluster traffic
+	ManagedByLabel      = "app.kubernetes.io/managed-by"
+	ManagedByLabelValue = "multi-cluster-traffic-controller"
+	// OwnerIDLabel holds the owner ID of the controller managing a DNSRecord,
+	// domain verification or certificate, so controllers with different owner
+	// IDs can share a control cluster
+	OwnerIDLabel = "kuadrant.io/owner-id"
+
+	// ClusterEndpointLabel and OwnerEndpointLabel identify the workload cluster
+	// and traffic object an endpoint was generated from
+	ClusterEndpointLabel = "kuadrant.io/cluster"
+	OwnerEndpointLabel   = "kuadrant.io/owner"
+
+	// HealthEndpointLabel is set to UnhealthyEndpointValue on endpoints that
+	// failed their health checks, which are published with UnhealthyWeight.
+	// The weight to restore once the endpoint recovers is kept in
+	// DrainedWeightEndpointLabel.
+	HealthEndpointLabel        = "kuadrant.io/health"
+	UnhealthyEndpointValue     = "unhealthy"
+	DrainedWeightEndpointLabel = "kuadrant.io/drained-weight"
+
+	// maxSetIdentifierLength is the longest set identifier Route53 accepts
+	maxSetIdentifierLength = 128
+
+	DefaultRecordTTL = 60
+	DefaultWeight    = "120"
+	UnhealthyWeight  = "0"
+)
+
+// reconcileDNS ensures every published host of the traffic object has an
+// endpoint for this cluster in the DNSRecord for that host, and removes the
+// endpoints of this cluster for hosts the object no longer publishes.
+func (r *Reconciler) reconcileDNS(ctx context.Context, t traffic.Interface, hosts []string) error {
+	owner := ownerKey(t)
+
+	endpointTemplate := r.endpointFor(t.GetDNSTargets(), owner)
+	if endpointTemplate == nil {
+		// the load balancer has not been provisioned yet, the cluster can't
+		// serve any of the hosts
+		hosts = nil
+	}
+
+	var policies []v1.TrafficPolicy
+	if len(hosts) > 0 {
+		var err error
+		if policies, err = r.listPolicies(ctx); err != nil {
+			return err
+		}
+	}
+	for _, host := range hosts {
+		policy := policyFor(policies, t, host)
+		endpoint := endpointTemplate.DeepCopy()
+		endpoint.DNSName = host
+		r.applyPolicy(endpoint, host, policy)
+		defaultGeo := ""
+		if policy != nil && policy.Spec.Geo != nil {
+			defaultGeo = policy.Spec.Geo.DefaultGeo
+		}
+		if err := r.ensureEndpoint(ctx, host, endpoint, defaultGeo); err != nil {
+			return err
+		}
+	}
+
+	records := &v1.DNSRecordList{}
+	if err := r.ControlClient.List(ctx, records, client.InNamespace(r.ReconcilerConfig.Namespace), client.MatchingLabels(r.managedLabels())); err != nil {
+		return err
+	}
+	for _, record := range records.Items {
+		if slice.ContainsString(hosts, record.Name) {
+			continue
+		}
+		for _, endpoint := range record.Spec.Endpoints {
+			if endpoint.Labels[ClusterEndpointLabel] == r.ClusterName && endpoint.Labels[OwnerEndpointLabel] == owner {
+				if err := r.removeEndpoint(ctx, record.Name, owner); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// managedLabels returns the labels of the DNSRecords, domain verifications
+// and certificates managed by the controller.
+func (r *Reconciler) managedLabels() map[string]string {
+	labels := map[string]string{ManagedByLabel: ManagedByLabelValue}
+	if r.ReconcilerConfig.OwnerID != "" {
+		labels[OwnerIDLabel] = r.ReconcilerConfig.OwnerID
+	}
+	return labels
+}
+
+// IsOwned returns whether the object is labelled with the owner ID, every
+// object is owned when the owner ID is empty.
+func IsOwned(obj metav1.Object, ownerID string) bool {
+	return ownerID == "" || obj.GetLabels()[OwnerIDLabel] == ownerID
+}
+
+// endpointFor returns the endpoint of the traffic object on this cluster for
+// the given targets with no DNS name or weight set. IP targets are published as an A record, otherwise the
+// first hostname target is published as a CNAME record. Returns nil when there
+// are no targets.
+func (r *Reconciler) endpointFor(targets []string, owner string) *v1.Endpoint {
+	var ips, hostnames []string
+	for _, target := range targets {
+		if net.ParseIP(target) != nil {
+			ips = append(ips, target)
+		} else {
+			hostnames = append(hostnames, target)
+		}
+	}
+	sort.Strings(ips)
+	sort.Strings(hostnames)
+
+	endpoint := &v1.Endpoint{
+		SetIdentifier: endpointSetIdentifier(r.ClusterName, owner),
+		RecordTTL:     DefaultRecordTTL,
+		Labels: v1.Labels{
+			ClusterEndpointLabel: r.ClusterName,
+			OwnerEndpointLabel:   owner,
+		},
+	}
+	switch {
+	case len(ips) > 0:
+		endpoint.RecordType = string(v1.ARecordType)
+		endpoint.Targets = ips
+	case len(hostnames) > 0:
+		endpoint.RecordType = string(v1.CNAMERecordType)
+		endpoint.Targets = hostnames[:1]
+	default:
+		return nil
+	}
+	return endpoint
+}
+
+// ensureEndpoint creates or updates the endpoint of the traffic object on this
+// cluster in the DNSRecord for the host, regenerating the geo layer of the record with the
+// given default geo. Fails when the record serves another traffic object.
+func (r *Reconciler) ensureEndpoint(ctx context.Context, host string, endpoint *v1.Endpoint, defaultGeo string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		record := &v1.DNSRecord{}
+		err := r.ControlClient.Get(ctx, client.ObjectKey{Namespace: r.ReconcilerConfig.Namespace, Name: host}, record)
+		if k8serrors.IsNotFound(err) {
+			record = &v1.DNSRecord{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      host,
+					Namespace: r.ReconcilerConfig.Namespace,
+					Labels:    r.managedLabels(),
+				},
+				Spec: v1.DNSRecordSpec{
+					Endpoints: layerEndpoints(host, []*v1.Endpoint{endpoint}, defaultGeo),
+				},
+			}
+			err = r.ControlClient.Create(ctx, record)
+			if k8serrors.IsAlreadyExists(err) {
+				// another cluster created the record first
+				return k8serrors.NewConflict(v1.GroupVersion.WithResource("dnsrecords").GroupResource(), host, err)
+			}
+			if err == nil {
+				log.Log.Info("created DNSRecord", "record", host, "cluster", r.ClusterName)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if record.Labels[ManagedByLabel] != ManagedByLabelValue {
+			return fmt.Errorf("DNSRecord %s/%s already exists and is not managed by %s", r.ReconcilerConfig.Namespace, host, ManagedByLabelValue)
+		}
+		if !IsOwned(record, r.ReconcilerConfig.OwnerID) {
+			return fmt.Errorf("DNSRecord %s/%s already exists and is owned by %q", r.ReconcilerConfig.Namespace, host, record.Labels[OwnerIDLabel])
+		}
+
+		updated := record.DeepCopy()
+		for _, existing := range record.Spec.Endpoints {
+			if owner := existing.Labels[OwnerEndpointLabel]; existing.Labels[LayerEndpointLabel] == "" && owner != endpoint.Labels[OwnerEndpointLabel] {
+				return fmt.Errorf("DNSRecord %s/%s already exists and is served by %s", r.ReconcilerConfig.Namespace, host, owner)
+			}
+		}
+		found := false
+		for i, existing := range updated.Spec.Endpoints {
+			if sameOwner(existing, endpoint) {
+				updated.Spec.Endpoints[i] = endpoint.DeepCopy()
+				if existing.Labels[HealthEndpointLabel] == UnhealthyEndpointValue {
+					// keep the endpoint drained until its health checks pass
+					drained := updated.Spec.Endpoints[i]
+					weight, _ := drained.GetProviderSpecificProperty(dnsAWS.ProviderSpecificWeight)
+					drained.Labels[HealthEndpointLabel] = UnhealthyEndpointValue
+					drained.Labels[DrainedWeightEndpointLabel] = weight.Value
+					drained.SetProviderSpecificProperty(dnsAWS.ProviderSpecificWeight, UnhealthyWeight)
+				}
+				found = true
+			}
+		}
+		if !found {
+			updated.Spec.Endpoints = append(updated.Spec.Endpoints, endpoint)
+		}
+		updated.Spec.Endpoints = layerEndpoints(host, updated.Spec.Endpoints, defaultGeo)
+		if equality.Semantic.DeepEqual(record, updated) {
+			return nil
+		}
+		return r.ControlClient.Update(ctx, updated)
+	})
+}
+
+// removeEndpoint removes the endpoint of the traffic object on this cluster
+// from the DNSRecord for the host, deleting the record when no endpoints
+// remain. The endpoints of the object on other clusters are kept.
+func (r *Reconciler) removeEndpoint(ctx context.Context, host, owner string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		record := &v1.DNSRecord{}
+		err := r.ControlClient.Get(ctx, client.ObjectKey{Namespace: r.ReconcilerConfig.Namespace, Name: host}, record)
+		if err != nil {
+			return client.IgnoreNotFound(err)
+		}
+
+		var endpoints []*v1.Endpoint
+		for _, endpoint := range record.Spec.Endpoints {
+			if endpoint.Labels[ClusterEndpointLabel] != r.ClusterName || endpoint.Labels[OwnerEndpointLabel] != owner {
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+		if len(endpoints) == len(record.Spec.Endpoints) {
+			return nil
+		}
+		endpoints = layerEndpoints(host, endpoints, "")
+		if len(endpoints) == 0 {
+			log.Log.Info("deleting DNSRecord", "record", host, "cluster", r.ClusterName)
+			// only delete the record if no other cluster added an endpoint
+			// since it was read
+			resourceVersion := record.ResourceVersion
+			return client.IgnoreNotFound(r.ControlClient.Delete(ctx, record, client.Preconditions{ResourceVersion: &resourceVersion}))
+		}
+		record.Spec.Endpoints = endpoints
+		return r.ControlClient.Update(ctx, record)
+	})
+}
+
+func (r *Reconciler) inManagedZone(host string) bool {
+	zone := strings.ToLower(strings.TrimSuffix(r.ReconcilerConfig.ManagedZone, "."))
+	return zone != "" && strings.HasSuffix(host, "."+zone)
+}
+
+// endpointSetIdentifier identifies the endpoint of a traffic object on a
+// cluster among the endpoints of a record, so objects on the same cluster
+// serving the same host have endpoints of their own. Identifiers longer than
+// Route53 accepts are hashed.
+func endpointSetIdentifier(cluster, owner string) string {
+	id := cluster + "/" + owner
+	if len(id) <= maxSetIdentifierLength {
+		return id
+	}
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
+
+// sameOwner returns whether the endpoints were generated from the same
+// traffic object on the same cluster.
+func sameOwner(a, b *v1.Endpoint) bool {
+	return a.Labels[OwnerEndpointLabel] != "" &&
+		a.Labels[ClusterEndpointLabel] == b.Labels[ClusterEndpointLabel] &&
+		a.Labels[OwnerEndpointLabel] == b.Labels[OwnerEndpointLabel]
+}
+
+// EndpointOwner returns the kind, namespace and name of the traffic object an
+// endpoint was generated from, all empty for endpoints with no owner.
+func EndpointOwner(endpoint *v1.Endpoint) (kind, namespace, name string) {
+	parts := strings.SplitN(endpoint.Labels[OwnerEndpointLabel], "/", 3)
+	if len(parts) != 3 {
+		return "", "", ""
+	}
+	return parts[0], parts[1], parts[2]
+}
+
+func ownerKey(t traffic.Interface) string {
+	return fmt.Sprintf("%s/%s", t.GetKind(), t.GetCacheKey())
+}
@@ -0,0 +1,333 @@
+package traffic
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	dnsAWS "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns/aws"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+// testOwner is the owner key of the test ingress
+const testOwner = "Ingress/test-namespace/test-ingress"
+
+// testHost and newTestHost are hosts generated for the test ingress in the
+// managed zone of the tests
+var (
+	testHost    = hostFor(testOwner, "test-ingress", 0, "example.com")
+	newTestHost = hostFor(testOwner, "test-ingress", 1, "example.com")
+)
+
+// testIngress returns a managed ingress serving the hosts, the first of which
+// is recorded as its managed host
+func testIngress(hosts []string, ips ...string) *traffic.Ingress {
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-ingress",
+			Namespace: "test-namespace",
+			Labels:    map[string]string{traffic.ManagedLabel: "true"},
+		},
+	}
+	if len(hosts) > 0 {
+		ingress.Annotations = map[string]string{traffic.ManagedHostAnnotation: hosts[0]}
+	}
+	for _, host := range hosts {
+		ingress.Spec.Rules = append(ingress.Spec.Rules, networkingv1.IngressRule{Host: host})
+	}
+	for _, ip := range ips {
+		ingress.Status.LoadBalancer.Ingress = append(ingress.Status.LoadBalancer.Ingress, corev1.LoadBalancerIngress{IP: ip})
+	}
+	return traffic.NewIngress(ingress)
+}
+
+func testControlClient(t *testing.T) client.Client {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := v1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).Build()
+}
+
+func getRecord(t *testing.T, c client.Client, host string) *v1.DNSRecord {
+	record := &v1.DNSRecord{}
+	err := c.Get(context.TODO(), client.ObjectKey{Namespace: "test-control", Name: host}, record)
+	if err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return nil
+		}
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return record
+}
+
+func publish(r *Reconciler, t traffic.Interface) error {
+	hosts, err := r.publishedHosts(context.TODO(), t)
+	if err != nil {
+		return err
+	}
+	return r.reconcileDNS(context.TODO(), t, hosts)
+}
+
+func Test_reconcileDNS(t *testing.T) {
+	controlClient := testControlClient(t)
+	config := ReconcilerConfig{Namespace: "test-control", ManagedZone: "example.com"}
+	clusterA := &Reconciler{ControlClient: controlClient, ClusterName: "cluster-a", ReconcilerConfig: config}
+	clusterB := &Reconciler{ControlClient: controlClient, ClusterName: "cluster-b", ReconcilerConfig: config}
+
+	// both clusters serve the host, unverified hosts outside the managed zone
+	// are not published
+	if err := publish(clusterA, testIngress([]string{testHost, "", "test.other.com"}, "1.1.1.1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := publish(clusterB, testIngress([]string{testHost}, "2.2.2.2")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	record := getRecord(t, controlClient, testHost)
+	if record == nil {
+		t.Fatalf("expected DNSRecord to be created")
+	}
+	if len(record.Spec.Endpoints) != 2 {
+		t.Fatalf("expected 2 endpoints, got: %v", len(record.Spec.Endpoints))
+	}
+	if getRecord(t, controlClient, "test.other.com") != nil {
+		t.Fatalf("expected no DNSRecord for a host outside the managed zone")
+	}
+	for _, endpoint := range record.Spec.Endpoints {
+		if endpoint.DNSName != testHost || endpoint.RecordType != string(v1.ARecordType) {
+			t.Errorf("unexpected endpoint '%v'", endpoint)
+		}
+		if expected := endpoint.Labels[ClusterEndpointLabel] + "/" + testOwner; endpoint.SetIdentifier != expected {
+			t.Errorf("expected set identifier '%v' got '%v'", expected, endpoint.SetIdentifier)
+		}
+	}
+
+	// cluster-a moves the ingress to a new host
+	if err := publish(clusterA, testIngress([]string{newTestHost}, "1.1.1.1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	record = getRecord(t, controlClient, testHost)
+	if len(record.Spec.Endpoints) != 1 || record.Spec.Endpoints[0].SetIdentifier != "cluster-b/"+testOwner {
+		t.Fatalf("expected only the cluster-b endpoint to remain, got: %v", record.Spec.Endpoints)
+	}
+	if getRecord(t, controlClient, newTestHost) == nil {
+		t.Fatalf("expected DNSRecord for the new host to be created")
+	}
+
+	// cluster-a stops managing the ingress
+	unmanaged := testIngress([]string{newTestHost}, "1.1.1.1")
+	unmanaged.SetLabels(nil)
+	if err := publish(clusterA, unmanaged); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if getRecord(t, controlClient, newTestHost) != nil {
+		t.Fatalf("expected DNSRecord to be deleted once the ingress is no longer managed")
+	}
+
+	// the ingress is deleted from cluster-b
+	deleted := testIngress([]string{testHost}, "2.2.2.2")
+	now := metav1.Now()
+	deleted.SetDeletionTimestamp(&now)
+	if err := publish(clusterB, deleted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if getRecord(t, controlClient, testHost) != nil {
+		t.Fatalf("expected DNSRecord to be deleted once no cluster serves the host")
+	}
+}
+
+func Test_reconcileDNSSharedHost(t *testing.T) {
+	controlClient := testControlClient(t)
+	r := &Reconciler{
+		ControlClient:    controlClient,
+		ClusterName:      "cluster-a",
+		ReconcilerConfig: ReconcilerConfig{Namespace: "test-control", ManagedZone: "example.com"},
+	}
+	if err := publish(r, testIngress([]string{testHost}, "1.1.1.1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// another ingress listing the host generated for the first is not
+	// published
+	other := testIngress([]string{testHost}, "2.2.2.2")
+	other.SetName("other-ingress")
+	if err := publish(r, other); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	record := getRecord(t, controlClient, testHost)
+	if record == nil || len(record.Spec.Endpoints) != 1 || record.Spec.Endpoints[0].SetIdentifier != "cluster-a/"+testOwner {
+		t.Fatalf("expected only the endpoint of the first ingress, got: %v", record)
+	}
+
+	// nor can its endpoint join the record of the first
+	endpoint := r.endpointFor([]string{"2.2.2.2"}, ownerKey(other))
+	endpoint.DNSName = testHost
+	r.applyPolicy(endpoint, testHost, nil)
+	if err := r.ensureEndpoint(context.TODO(), testHost, endpoint, ""); err == nil {
+		t.Errorf("expected an error adding an endpoint to the record of another owner")
+	}
+	if record := getRecord(t, controlClient, testHost); len(record.Spec.Endpoints) != 1 {
+		t.Errorf("expected the record of another owner to be left alone, got: %v", record.Spec.Endpoints)
+	}
+}
+
+func Test_endpointFor(t *testing.T) {
+	r := &Reconciler{ClusterName: "cluster-a"}
+
+	tests := []struct {
+		name       string
+		targets    []string
+		recordType string
+		expect     []string
+	}{
+		{
+			name:    "no targets",
+			targets: nil,
+		},
+		{
+			name:       "ip targets",
+			targets:    []string{"2.2.2.2", "lb.example.com", "1.1.1.1"},
+			recordType: string(v1.ARecordType),
+			expect:     []string{"1.1.1.1", "2.2.2.2"},
+		},
+		{
+			name:       "hostname targets",
+			targets:    []string{"lb-b.example.com", "lb-a.example.com"},
+			recordType: string(v1.CNAMERecordType),
+			expect:     []string{"lb-a.example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint := r.endpointFor(tt.targets, "Ingress/test-namespace/test-ingress")
+			if tt.expect == nil {
+				if endpoint != nil {
+					t.Errorf("expected no endpoint, got: %v", endpoint)
+				}
+				return
+			}
+			if endpoint.RecordType != tt.recordType {
+				t.Errorf("expected record type '%v' got '%v'", tt.recordType, endpoint.RecordType)
+			}
+			if len(endpoint.Targets) != len(tt.expect) {
+				t.Fatalf("expected targets '%v' got '%v'", tt.expect, endpoint.Targets)
+			}
+			for i := range tt.expect {
+				if endpoint.Targets[i] != tt.expect[i] {
+					t.Errorf("expected targets '%v' got '%v'", tt.expect, endpoint.Targets)
+				}
+			}
+		})
+	}
+}
+
+func Test_ensureEndpointKeepsUnhealthyEndpointDrained(t *testing.T) {
+	controlClient := testControlClient(t)
+	r := &Reconciler{ControlClient: controlClient, ClusterName: "cluster-a", ReconcilerConfig: ReconcilerConfig{Namespace: "test-control"}}
+	owner := "Ingress/test-namespace/test-ingress"
+
+	endpoint := r.endpointFor([]string{"1.1.1.1"}, owner)
+	endpoint.DNSName = "test.example.com"
+	r.applyPolicy(endpoint, "test.example.com", nil)
+	if err := r.ensureEndpoint(context.TODO(), "test.example.com", endpoint, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the health checks drain the endpoint
+	record := getRecord(t, controlClient, "test.example.com")
+	record.Spec.Endpoints[0].Labels[HealthEndpointLabel] = UnhealthyEndpointValue
+	record.Spec.Endpoints[0].SetProviderSpecificProperty(dnsAWS.ProviderSpecificWeight, UnhealthyWeight)
+	if err := controlClient.Update(context.TODO(), record); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the workload cluster moves to a new address
+	endpoint = r.endpointFor([]string{"2.2.2.2"}, owner)
+	endpoint.DNSName = "test.example.com"
+	r.applyPolicy(endpoint, "test.example.com", nil)
+	if err := r.ensureEndpoint(context.TODO(), "test.example.com", endpoint, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	record = getRecord(t, controlClient, "test.example.com")
+	updated := record.Spec.Endpoints[0]
+	if updated.Targets[0] != "2.2.2.2" {
+		t.Errorf("expected the endpoint target to be updated, got: %v", updated.Targets)
+	}
+	if weight, _ := updated.GetProviderSpecificProperty(dnsAWS.ProviderSpecificWeight); weight.Value != UnhealthyWeight || updated.Labels[HealthEndpointLabel] != UnhealthyEndpointValue {
+		t.Errorf("expected the endpoint to stay drained, got: %v", updated)
+	}
+	if updated.Labels[DrainedWeightEndpointLabel] != DefaultWeight {
+		t.Errorf("expected the weight to restore '%v' got '%v'", DefaultWeight, updated.Labels[DrainedWeightEndpointLabel])
+	}
+}
+
+func Test_reconcileDNSWithOwnerID(t *testing.T) {
+	controlClient := testControlClient(t)
+	owner1 := &Reconciler{ControlClient: controlClient, ClusterName: "cluster-a", ReconcilerConfig: ReconcilerConfig{Namespace: "test-control", ManagedZone: "example.com", OwnerID: "owner-1"}}
+	owner2 := &Reconciler{ControlClient: controlClient, ClusterName: "cluster-b", ReconcilerConfig: ReconcilerConfig{Namespace: "test-control", ManagedZone: "example.com", OwnerID: "owner-2"}}
+
+	if err := publish(owner1, testIngress([]string{testHost}, "1.1.1.1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	record := getRecord(t, controlClient, testHost)
+	if record == nil {
+		t.Fatalf("expected DNSRecord to be created")
+	}
+	if record.Labels[OwnerIDLabel] != "owner-1" {
+		t.Errorf("expected owner ID 'owner-1' got '%v'", record.Labels[OwnerIDLabel])
+	}
+
+	if err := publish(owner2, testIngress([]string{testHost}, "2.2.2.2")); err == nil {
+		t.Errorf("expected an error publishing a record of another owner")
+	}
+	if record := getRecord(t, controlClient, testHost); len(record.Spec.Endpoints) != 1 {
+		t.Errorf("expected the record of another owner to be left alone, got: %v", record.Spec.Endpoints)
+	}
+
+	// a record without an owner ID is not owned
+	delete(record.Labels, OwnerIDLabel)
+	if err := controlClient.Update(context.TODO(), record); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := publish(owner1, testIngress([]string{testHost}, "1.1.1.1")); err == nil {
+		t.Errorf("expected an error publishing a record without an owner ID")
+	}
+	if record := getRecord(t, controlClient, testHost); record.Labels[OwnerIDLabel] != "" {
+		t.Errorf("expected the record not to be labelled with the owner ID, got: %v", record.Labels)
+	}
+}
+
+func Test_sameOwner(t *testing.T) {
+	endpoint := func(cluster, owner string) *v1.Endpoint {
+		return &v1.Endpoint{Labels: map[string]string{ClusterEndpointLabel: cluster, OwnerEndpointLabel: owner}}
+	}
+	tests := []struct {
+		name   string
+		a, b   *v1.Endpoint
+		expect bool
+	}{
+		{name: "same owner", a: endpoint("cluster-a", "Ingress/ns/a"), b: endpoint("cluster-a", "Ingress/ns/a"), expect: true},
+		{name: "other object", a: endpoint("cluster-a", "Ingress/ns/a"), b: endpoint("cluster-a", "Ingress/ns/b")},
+		{name: "other cluster", a: endpoint("cluster-a", "Ingress/ns/a"), b: endpoint("cluster-b", "Ingress/ns/a")},
+		{name: "no owner", a: endpoint("cluster-a", ""), b: endpoint("cluster-a", "")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if same := sameOwner(tt.a, tt.b); same != tt.expect {
+				t.Errorf("expected same owner %v got %v", tt.expect, same)
+			}
+		})
+	}
+}
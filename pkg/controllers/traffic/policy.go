@@ -0,0 +1,157 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package traffic
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base32"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+const (
+	// maxLabelLength is the maximum length of a single DNS label
+	maxLabelLength = 63
+	// hashLength is the length of the hash suffix of generated host labels
+	hashLength = 8
+	// maxHostAttempts is the number of hosts tried before giving up on
+	// finding one that is not claimed by another traffic object
+	maxHostAttempts = 5
+)
+
+// manageHost assigns a host generated under the managed zone to the traffic
+// object. The generated host is recorded on the object so it stays the same
+// for the lifetime of the object. A recorded host that was not generated for
+// the object is replaced.
+func (r *Reconciler) manageHost(ctx context.Context, t traffic.Interface) error {
+	if r.ReconcilerConfig.ManagedZone == "" {
+		log.Log.Info("no managed zone configured, skipping host management", "kind", t.GetKind(), "name", t.GetCacheKey())
+		return nil
+	}
+
+	host := metadata.GetAnnotation(t, traffic.ManagedHostAnnotation)
+	if host != "" && !r.isHostFor(t, host) {
+		log.Log.Info("replacing managed host that was not generated for the object", "host", host, "owner", ownerKey(t))
+		host = ""
+	}
+	if host == "" {
+		var err error
+		host, err = r.generateHost(ctx, t)
+		if err != nil {
+			return err
+		}
+	}
+	if err := t.AddManagedHost(host); err != nil {
+		return fmt.Errorf("failed to add managed host %s to %s %s: %w", host, t.GetKind(), t.GetCacheKey(), err)
+	}
+	metadata.AddAnnotation(t, traffic.ManagedHostAnnotation, host)
+	return nil
+}
+
+// unmanageHost removes the generated host from a traffic object that is no
+// longer opted in to management.
+func (r *Reconciler) unmanageHost(t traffic.Interface) error {
+	host := metadata.GetAnnotation(t, traffic.ManagedHostAnnotation)
+	if host == "" {
+		return nil
+	}
+	if err := t.RemoveManagedHost(host); err != nil {
+		return fmt.Errorf("failed to remove managed host %s from %s %s: %w", host, t.GetKind(), t.GetCacheKey(), err)
+	}
+	metadata.RemoveAnnotation(t, traffic.ManagedHostAnnotation)
+	return nil
+}
+
+// generateHost returns a host under the managed zone for the traffic object.
+// The host is derived from the kind, namespace and name of the object, so the
+// same object on every workload cluster gets the same host, and is skipped if
+// a DNSRecord for it is already published for a different object.
+func (r *Reconciler) generateHost(ctx context.Context, t traffic.Interface) (string, error) {
+	owner := ownerKey(t)
+	for attempt := 0; attempt < maxHostAttempts; attempt++ {
+		host := hostFor(owner, t.GetName(), attempt, r.ReconcilerConfig.ManagedZone)
+		claimed, err := r.isClaimed(ctx, host, owner)
+		if err != nil {
+			return "", err
+		}
+		if !claimed {
+			return host, nil
+		}
+		log.Log.Info("generated host is claimed by another object, retrying", "host", host, "owner", owner)
+	}
+	return "", fmt.Errorf("failed to generate a host for %s after %d attempts", owner, maxHostAttempts)
+}
+
+// isClaimed returns whether the DNSRecord for the host has endpoints from a
+// traffic object other than owner.
+func (r *Reconciler) isClaimed(ctx context.Context, host, owner string) (bool, error) {
+	record := &v1.DNSRecord{}
+	err := r.ControlClient.Get(ctx, client.ObjectKey{Namespace: r.ReconcilerConfig.Namespace, Name: host}, record)
+	if err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	for _, endpoint := range record.Spec.Endpoints {
+		if endpoint.Labels[LayerEndpointLabel] == "" && endpoint.Labels[OwnerEndpointLabel] != owner {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isGeneratedHost returns whether the host is the managed host generated for
+// the traffic object, and no other object claimed it since.
+func (r *Reconciler) isGeneratedHost(ctx context.Context, t traffic.Interface, host string) (bool, error) {
+	if host != metadata.GetAnnotation(t, traffic.ManagedHostAnnotation) || !r.isHostFor(t, host) {
+		return false, nil
+	}
+	claimed, err := r.isClaimed(ctx, host, ownerKey(t))
+	return !claimed, err
+}
+
+// isHostFor returns whether generateHost may return the host for the traffic
+// object.
+func (r *Reconciler) isHostFor(t traffic.Interface, host string) bool {
+	owner := ownerKey(t)
+	for attempt := 0; attempt < maxHostAttempts; attempt++ {
+		if hostFor(owner, t.GetName(), attempt, r.ReconcilerConfig.ManagedZone) == host {
+			return true
+		}
+	}
+	return false
+}
+
+// hostFor returns the host for the given attempt, made of the object name
+// and a hash of the owner and attempt so it is unique per owner.
+func hostFor(owner, name string, attempt int, zone string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%d", owner, attempt)))
+	hash := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(sum[:]))[:hashLength]
+
+	prefix := strings.ToLower(name)
+	if len(prefix) > maxLabelLength-hashLength-1 {
+		prefix = prefix[:maxLabelLength-hashLength-1]
+	}
+	prefix = strings.Trim(strings.ReplaceAll(prefix, ".", "-"), "-")
+	return fmt.Sprintf("%s-%s.%s", prefix, hash, strings.TrimSuffix(zone, "."))
+}
@@ -0,0 +1,146 @@
+package traffic
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+func Test_manageHost(t *testing.T) {
+	r := &Reconciler{
+		ControlClient:    testControlClient(t),
+		ReconcilerConfig: ReconcilerConfig{Namespace: "test-control", ManagedZone: "example.com"},
+	}
+	rule := networkingv1.IngressRule{
+		Host: "app.team.com",
+		IngressRuleValue: networkingv1.IngressRuleValue{
+			HTTP: &networkingv1.HTTPIngressRuleValue{
+				Paths: []networkingv1.HTTPIngressPath{{Path: "/"}},
+			},
+		},
+	}
+	ingress := traffic.NewIngress(&networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-ingress",
+			Namespace: "test-namespace",
+			Labels:    map[string]string{traffic.ManagedLabel: "true"},
+		},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{rule},
+		},
+	})
+
+	if err := r.manageHost(context.TODO(), ingress); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	host := ingress.GetAnnotations()[traffic.ManagedHostAnnotation]
+	if !strings.HasPrefix(host, "test-ingress-") || !strings.HasSuffix(host, ".example.com") {
+		t.Fatalf("expected generated host under the managed zone, got '%v'", host)
+	}
+	if len(ingress.Spec.Rules) != 2 || !reflect.DeepEqual(ingress.Spec.Rules[0], rule) {
+		t.Fatalf("expected the original rule to be preserved, got: %v", ingress.Spec.Rules)
+	}
+	if ingress.Spec.Rules[1].Host != host || !reflect.DeepEqual(ingress.Spec.Rules[1].HTTP, rule.HTTP) {
+		t.Fatalf("expected a copy of the original rule for the generated host, got: %v", ingress.Spec.Rules[1])
+	}
+
+	// managing the host again is a no-op
+	if err := r.manageHost(context.TODO(), ingress); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ingress.Spec.Rules) != 2 {
+		t.Fatalf("expected 2 rules, got: %v", len(ingress.Spec.Rules))
+	}
+
+	if err := r.unmanageHost(ingress); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(ingress.Spec.Rules, []networkingv1.IngressRule{rule}) {
+		t.Fatalf("expected only the original rule to remain, got: %v", ingress.Spec.Rules)
+	}
+	if _, ok := ingress.GetAnnotations()[traffic.ManagedHostAnnotation]; ok {
+		t.Fatalf("expected the managed host annotation to be removed")
+	}
+}
+
+func Test_manageHostReplacesPresetHost(t *testing.T) {
+	r := &Reconciler{
+		ControlClient:    testControlClient(t),
+		ReconcilerConfig: ReconcilerConfig{Namespace: "test-control", ManagedZone: "example.com"},
+	}
+	ingress := testIngress(nil)
+	ingress.SetAnnotations(map[string]string{traffic.ManagedHostAnnotation: "other-ingress-abcdefgh.example.com"})
+
+	if err := r.manageHost(context.TODO(), ingress); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if host := ingress.GetAnnotations()[traffic.ManagedHostAnnotation]; host != testHost {
+		t.Errorf("expected the generated host '%v' got '%v'", testHost, host)
+	}
+	if hosts := ingress.GetHosts(); len(hosts) != 1 || hosts[0] != testHost {
+		t.Errorf("expected only the generated host to be served, got: %v", hosts)
+	}
+}
+
+func Test_generateHost(t *testing.T) {
+	controlClient := testControlClient(t)
+	r := &Reconciler{
+		ControlClient:    controlClient,
+		ReconcilerConfig: ReconcilerConfig{Namespace: "test-control", ManagedZone: "example.com."},
+	}
+	ingress := traffic.NewIngress(&networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      strings.Repeat("a", 60),
+			Namespace: "test-namespace",
+		},
+	})
+
+	host, err := r.generateHost(context.TODO(), ingress)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	label := strings.Split(host, ".")[0]
+	if len(label) > maxLabelLength {
+		t.Errorf("expected label of at most %v characters, got: %v", maxLabelLength, len(label))
+	}
+	if !strings.HasSuffix(host, ".example.com") {
+		t.Errorf("expected host under the managed zone, got: %v", host)
+	}
+
+	// the host is stable
+	again, err := r.generateHost(context.TODO(), ingress)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again != host {
+		t.Errorf("expected the same host '%v' got '%v'", host, again)
+	}
+
+	// another object already published the host
+	err = controlClient.Create(context.TODO(), &v1.DNSRecord{
+		ObjectMeta: metav1.ObjectMeta{Name: host, Namespace: "test-control"},
+		Spec: v1.DNSRecordSpec{
+			Endpoints: []*v1.Endpoint{{
+				DNSName: host,
+				Labels:  v1.Labels{OwnerEndpointLabel: "Ingress/other-namespace/other-ingress"},
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	collision, err := r.generateHost(context.TODO(), ingress)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if collision == host {
+		t.Errorf("expected a different host to the claimed host '%v'", host)
+	}
+}
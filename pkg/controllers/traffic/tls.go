@@ -0,0 +1,311 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package traffic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+const (
+	// CertificateRequeueDelay is how long to wait before checking again for
+	// the secret of a certificate that has not been issued yet
+	CertificateRequeueDelay = 10 * time.Second
+
+	// SecretOwnersAnnotation lists the traffic objects using the copy of a
+	// certificate secret on a workload cluster, comma separated. The copy is
+	// deleted once no object uses it.
+	SecretOwnersAnnotation = "kuadrant.io/tls-owners"
+
+	// CertificateSecretSuffix is appended to a host to name the secret of
+	// its certificate
+	CertificateSecretSuffix = "-tls"
+)
+
+// CertificateGVK is the cert-manager certificate requested for each published
+// host
+var CertificateGVK = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+
+// reconcileTLS requests a certificate on the control cluster for every
+// published host of the traffic object and, once issued, copies its secret
+// into the namespace of the object on the workload cluster and adds TLS for
+// the host. TLS is removed for hosts that are no longer published, deleting
+// the copied secret once no other object in the namespace uses it and the
+// certificate once no cluster publishes the host. A host that fails does not
+// hold up the others. Returns true when a certificate has not been issued
+// yet and the object needs handling again.
+func (r *Reconciler) reconcileTLS(ctx context.Context, t traffic.Interface, hosts []string) (bool, error) {
+	if r.ReconcilerConfig.ClusterIssuer == "" {
+		return false, nil
+	}
+	// no certificate is requested for kinds that cannot terminate TLS
+	if err := t.RemoveTLS(nil); errors.Is(err, traffic.ErrTLSNotSupported) {
+		return false, nil
+	}
+
+	var tlsHosts []string
+	for _, host := range strings.Split(metadata.GetAnnotation(t, traffic.TLSHostsAnnotation), ",") {
+		if host != "" {
+			tlsHosts = append(tlsHosts, host)
+		}
+	}
+
+	for _, host := range tlsHosts {
+		if slice.ContainsString(hosts, host) {
+			continue
+		}
+		if err := t.RemoveTLS([]string{host}); err != nil {
+			return false, fmt.Errorf("failed to remove TLS for %s from %s %s: %w", host, t.GetKind(), t.GetCacheKey(), err)
+		}
+		if err := r.deleteWorkloadSecret(ctx, t.GetNamespace(), host, ownerKey(t)); err != nil {
+			return false, err
+		}
+		if err := r.deleteUnusedCertificate(ctx, host); err != nil {
+			return false, err
+		}
+		tlsHosts = slice.RemoveString(tlsHosts, host)
+	}
+
+	pending := false
+	var errs []error
+	for _, host := range hosts {
+		if err := r.ensureCertificate(ctx, host); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		source := &corev1.Secret{}
+		err := r.ControlClient.Get(ctx, client.ObjectKey{Namespace: r.ReconcilerConfig.Namespace, Name: certificateSecretName(host)}, source)
+		if k8serrors.IsNotFound(err) || (err == nil && len(source.Data[corev1.TLSCertKey]) == 0) {
+			log.Log.V(1).Info("waiting for certificate to be issued", "host", host)
+			pending = true
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		// the secret is copied before the object references it
+		secret := workloadSecretFor(source, t.GetNamespace(), host)
+		if err := r.ensureWorkloadSecret(ctx, secret, ownerKey(t)); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := t.AddTLS(host, secret); err != nil {
+			errs = append(errs, fmt.Errorf("failed to add TLS for %s to %s %s: %w", host, t.GetKind(), t.GetCacheKey(), err))
+			continue
+		}
+		if !slice.ContainsString(tlsHosts, host) {
+			tlsHosts = append(tlsHosts, host)
+		}
+	}
+
+	if len(tlsHosts) == 0 {
+		metadata.RemoveAnnotation(t, traffic.TLSHostsAnnotation)
+	} else {
+		sort.Strings(tlsHosts)
+		metadata.AddAnnotation(t, traffic.TLSHostsAnnotation, strings.Join(tlsHosts, ","))
+	}
+	return pending, utilerrors.NewAggregate(errs)
+}
+
+// ensureCertificate requests a certificate for the host from the configured
+// cluster issuer. The certificate is shared by every workload cluster serving
+// the host.
+func (r *Reconciler) ensureCertificate(ctx context.Context, host string) error {
+	certificate := &unstructured.Unstructured{}
+	certificate.SetGroupVersionKind(CertificateGVK)
+	err := r.ControlClient.Get(ctx, client.ObjectKey{Namespace: r.ReconcilerConfig.Namespace, Name: host}, certificate)
+	if err == nil || !k8serrors.IsNotFound(err) {
+		return err
+	}
+	secretLabels := map[string]interface{}{}
+	for label, value := range r.managedLabels() {
+		secretLabels[label] = value
+	}
+
+	certificate.SetName(host)
+	certificate.SetNamespace(r.ReconcilerConfig.Namespace)
+	certificate.SetLabels(r.managedLabels())
+	certificate.Object["spec"] = map[string]interface{}{
+		"secretName": certificateSecretName(host),
+		"dnsNames":   []interface{}{host},
+		"issuerRef": map[string]interface{}{
+			"group": CertificateGVK.Group,
+			"kind":  "ClusterIssuer",
+			"name":  r.ReconcilerConfig.ClusterIssuer,
+		},
+		// label the issued secret so renewals are synced to the workload
+		// clusters
+		"secretTemplate": map[string]interface{}{
+			"labels": secretLabels,
+		},
+	}
+	err = r.ControlClient.Create(ctx, certificate)
+	if err == nil {
+		log.Log.Info("requested certificate", "host", host, "issuer", r.ReconcilerConfig.ClusterIssuer)
+	}
+	if k8serrors.IsAlreadyExists(err) {
+		// another cluster requested the certificate first
+		return nil
+	}
+	return err
+}
+
+// deleteUnusedCertificate deletes the certificate for the host, and its
+// issued secret, once no cluster publishes the host, which is when the
+// DNSRecord for the host has been deleted.
+func (r *Reconciler) deleteUnusedCertificate(ctx context.Context, host string) error {
+	err := r.ControlClient.Get(ctx, client.ObjectKey{Namespace: r.ReconcilerConfig.Namespace, Name: host}, &v1.DNSRecord{})
+	if err == nil || !k8serrors.IsNotFound(err) {
+		return err
+	}
+
+	certificate := &unstructured.Unstructured{}
+	certificate.SetGroupVersionKind(CertificateGVK)
+	err = r.ControlClient.Get(ctx, client.ObjectKey{Namespace: r.ReconcilerConfig.Namespace, Name: host}, certificate)
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	if err == nil && certificate.GetLabels()[ManagedByLabel] == ManagedByLabelValue && IsOwned(certificate, r.ReconcilerConfig.OwnerID) {
+		log.Log.Info("deleting certificate", "host", host)
+		if err := r.ControlClient.Delete(ctx, certificate); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+
+	// cert-manager leaves the issued secret behind
+	source := &corev1.Secret{}
+	err = r.ControlClient.Get(ctx, client.ObjectKey{Namespace: r.ReconcilerConfig.Namespace, Name: certificateSecretName(host)}, source)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if source.Labels[ManagedByLabel] != ManagedByLabelValue || !IsOwned(source, r.ReconcilerConfig.OwnerID) {
+		return nil
+	}
+	return client.IgnoreNotFound(r.ControlClient.Delete(ctx, source))
+}
+
+// ensureWorkloadSecret creates or updates the copy of a certificate secret on
+// the workload cluster, recording the traffic object as one of its owners.
+func (r *Reconciler) ensureWorkloadSecret(ctx context.Context, secret *corev1.Secret, owner string) error {
+	existing := &corev1.Secret{}
+	err := r.WorkloadClient.Get(ctx, client.ObjectKeyFromObject(secret), existing)
+	if k8serrors.IsNotFound(err) {
+		log.Log.Info("copying certificate secret to workload cluster", "secret", secret.Namespace+"/"+secret.Name, "cluster", r.ClusterName)
+		metadata.AddAnnotation(secret, SecretOwnersAnnotation, owner)
+		return r.WorkloadClient.Create(ctx, secret)
+	}
+	if err != nil {
+		return err
+	}
+	if existing.Labels[ManagedByLabel] != ManagedByLabelValue {
+		return fmt.Errorf("secret %s/%s already exists on cluster %s and is not managed by %s", secret.Namespace, secret.Name, r.ClusterName, ManagedByLabelValue)
+	}
+	owners := secretOwners(existing)
+	if equality.Semantic.DeepEqual(existing.Data, secret.Data) && slice.ContainsString(owners, owner) {
+		return nil
+	}
+	existing.Data = secret.Data
+	setSecretOwners(existing, append(slice.RemoveString(owners, owner), owner))
+	return r.WorkloadClient.Update(ctx, existing)
+}
+
+// deleteWorkloadSecret removes the traffic object from the owners of the copy
+// of the certificate secret for the host in the namespace on the workload
+// cluster, deleting the copy once it has no owners left.
+func (r *Reconciler) deleteWorkloadSecret(ctx context.Context, namespace, host, owner string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret := &corev1.Secret{}
+		err := r.WorkloadClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: certificateSecretName(host)}, secret)
+		if errors.Is(err, traffic.ErrOutOfScope) {
+			// the object left the scope of the controller, which no longer
+			// has access to the copy
+			log.Log.Info("leaving certificate secret outside the watch scope", "secret", namespace+"/"+certificateSecretName(host), "cluster", r.ClusterName)
+			return nil
+		}
+		if err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		if secret.Labels[ManagedByLabel] != ManagedByLabelValue {
+			return nil
+		}
+		owners := slice.RemoveString(secretOwners(secret), owner)
+		if len(owners) > 0 {
+			setSecretOwners(secret, owners)
+			return r.WorkloadClient.Update(ctx, secret)
+		}
+		log.Log.Info("deleting certificate secret from workload cluster", "secret", namespace+"/"+secret.Name, "cluster", r.ClusterName)
+		// only delete the copy if no other object started using it since it
+		// was read
+		resourceVersion := secret.ResourceVersion
+		return client.IgnoreNotFound(r.WorkloadClient.Delete(ctx, secret, client.Preconditions{ResourceVersion: &resourceVersion}))
+	})
+}
+
+func secretOwners(secret *corev1.Secret) []string {
+	var owners []string
+	for _, owner := range strings.Split(metadata.GetAnnotation(secret, SecretOwnersAnnotation), ",") {
+		if owner != "" {
+			owners = append(owners, owner)
+		}
+	}
+	return owners
+}
+
+func setSecretOwners(secret *corev1.Secret, owners []string) {
+	sort.Strings(owners)
+	metadata.AddAnnotation(secret, SecretOwnersAnnotation, strings.Join(owners, ","))
+}
+
+// workloadSecretFor returns the copy of the certificate secret for the host in
+// the namespace of a workload cluster.
+func workloadSecretFor(source *corev1.Secret, namespace, host string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      certificateSecretName(host),
+			Namespace: namespace,
+			Labels:    map[string]string{ManagedByLabel: ManagedByLabelValue},
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: source.DeepCopy().Data,
+	}
+}
+
+func certificateSecretName(host string) string {
+	return host + CertificateSecretSuffix
+}
@@ -0,0 +1,248 @@
+package traffic
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+func Test_reconcileTLS(t *testing.T) {
+	controlClient := testControlClient(t)
+	workloadClient := fake.NewClientBuilder().Build()
+	r := &Reconciler{
+		ControlClient:    controlClient,
+		WorkloadClient:   workloadClient,
+		ClusterName:      "cluster-a",
+		ReconcilerConfig: ReconcilerConfig{Namespace: "test-control", ManagedZone: "example.com", ClusterIssuer: "test-issuer"},
+	}
+	ingress := testIngress([]string{"test.example.com"}, "1.1.1.1")
+	hosts := []string{"test.example.com"}
+
+	// the certificate is requested and the object waits for it to be issued
+	pending, err := r.reconcileTLS(context.TODO(), ingress, hosts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !pending {
+		t.Fatalf("expected to wait for the certificate to be issued")
+	}
+	certificate := &unstructured.Unstructured{}
+	certificate.SetGroupVersionKind(CertificateGVK)
+	if err := controlClient.Get(context.TODO(), client.ObjectKey{Namespace: "test-control", Name: "test.example.com"}, certificate); err != nil {
+		t.Fatalf("expected the certificate to be requested: %v", err)
+	}
+	if issuer, _, _ := unstructured.NestedString(certificate.Object, "spec", "issuerRef", "name"); issuer != "test-issuer" {
+		t.Errorf("expected issuer 'test-issuer' got '%v'", issuer)
+	}
+	if len(ingress.Spec.TLS) != 0 {
+		t.Errorf("expected no TLS before the certificate is issued, got: %v", ingress.Spec.TLS)
+	}
+
+	// cert-manager issues the certificate
+	issued := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test-control", Name: "test.example.com-tls"},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       []byte("cert"),
+			corev1.TLSPrivateKeyKey: []byte("key"),
+		},
+	}
+	if err := controlClient.Create(context.TODO(), issued); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pending, err = r.reconcileTLS(context.TODO(), ingress, hosts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pending {
+		t.Fatalf("expected the certificate to be issued")
+	}
+	copied := &corev1.Secret{}
+	if err := workloadClient.Get(context.TODO(), client.ObjectKey{Namespace: "test-namespace", Name: "test.example.com-tls"}, copied); err != nil {
+		t.Fatalf("expected the secret to be copied to the workload cluster: %v", err)
+	}
+	if string(copied.Data[corev1.TLSCertKey]) != "cert" {
+		t.Errorf("expected the copied certificate 'cert' got '%s'", copied.Data[corev1.TLSCertKey])
+	}
+	if len(ingress.Spec.TLS) != 1 || ingress.Spec.TLS[0].SecretName != "test.example.com-tls" {
+		t.Fatalf("expected TLS to be added for the host, got: %v", ingress.Spec.TLS)
+	}
+	if ingress.GetAnnotations()[traffic.TLSHostsAnnotation] != "test.example.com" {
+		t.Errorf("expected the TLS host to be recorded, got: %v", ingress.GetAnnotations())
+	}
+
+	// another object in the namespace uses the same copy
+	other := testIngress([]string{"test.example.com"}, "1.1.1.1")
+	other.SetName("other-ingress")
+	if _, err := r.reconcileTLS(context.TODO(), other, hosts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the host is no longer published by the first object but still has an
+	// endpoint in the DNSRecord
+	record := &v1.DNSRecord{ObjectMeta: metav1.ObjectMeta{Namespace: "test-control", Name: "test.example.com"}}
+	if err := controlClient.Create(context.TODO(), record); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := r.reconcileTLS(context.TODO(), ingress, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ingress.Spec.TLS) != 0 {
+		t.Errorf("expected TLS to be removed, got: %v", ingress.Spec.TLS)
+	}
+	if _, ok := ingress.GetAnnotations()[traffic.TLSHostsAnnotation]; ok {
+		t.Errorf("expected the TLS hosts annotation to be removed")
+	}
+	if err := workloadClient.Get(context.TODO(), client.ObjectKey{Namespace: "test-namespace", Name: "test.example.com-tls"}, copied); err != nil {
+		t.Fatalf("expected the copied secret to be kept for the other object: %v", err)
+	}
+	if owners := copied.Annotations[SecretOwnersAnnotation]; owners != ownerKey(other) {
+		t.Errorf("expected the owners '%v' got '%v'", ownerKey(other), owners)
+	}
+	if err := controlClient.Get(context.TODO(), client.ObjectKeyFromObject(certificate), certificate); err != nil {
+		t.Errorf("expected the certificate to be kept while the host is published: %v", err)
+	}
+
+	// the host is no longer published by any object
+	if err := controlClient.Delete(context.TODO(), record); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := r.reconcileTLS(context.TODO(), other, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = workloadClient.Get(context.TODO(), client.ObjectKey{Namespace: "test-namespace", Name: "test.example.com-tls"}, copied)
+	if !k8serrors.IsNotFound(err) {
+		t.Errorf("expected the copied secret to be deleted, got: %v", err)
+	}
+	err = controlClient.Get(context.TODO(), client.ObjectKeyFromObject(certificate), certificate)
+	if !k8serrors.IsNotFound(err) {
+		t.Errorf("expected the certificate to be deleted, got: %v", err)
+	}
+}
+
+func Test_ensureCertificateWithOwnerID(t *testing.T) {
+	controlClient := testControlClient(t)
+	legacy := &Reconciler{
+		ControlClient:    controlClient,
+		ClusterName:      "cluster-a",
+		ReconcilerConfig: ReconcilerConfig{Namespace: "test-control", ManagedZone: "example.com", ClusterIssuer: "test-issuer"},
+	}
+	if err := legacy.ensureCertificate(context.TODO(), "test.example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the certificate requested without an owner ID is not owned
+	owned := &Reconciler{ControlClient: controlClient, ClusterName: "cluster-a", ReconcilerConfig: legacy.ReconcilerConfig}
+	owned.ReconcilerConfig.OwnerID = "owner-1"
+	if err := owned.ensureCertificate(context.TODO(), "test.example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := owned.deleteUnusedCertificate(context.TODO(), "test.example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	certificate := &unstructured.Unstructured{}
+	certificate.SetGroupVersionKind(CertificateGVK)
+	if err := controlClient.Get(context.TODO(), client.ObjectKey{Namespace: "test-control", Name: "test.example.com"}, certificate); err != nil {
+		t.Fatalf("expected the certificate without an owner ID to be kept: %v", err)
+	}
+	if _, ok := certificate.GetLabels()[OwnerIDLabel]; ok {
+		t.Errorf("expected the certificate not to be labelled with the owner ID, got: %v", certificate.GetLabels())
+	}
+	if err := controlClient.Delete(context.TODO(), certificate); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := owned.ensureCertificate(context.TODO(), "test.example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := controlClient.Get(context.TODO(), client.ObjectKeyFromObject(certificate), certificate); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if owner, _, _ := unstructured.NestedString(certificate.Object, "spec", "secretTemplate", "labels", OwnerIDLabel); owner != "owner-1" {
+		t.Errorf("expected the issued secret to be labelled with the owner ID, got '%v'", owner)
+	}
+
+	// the certificate of another owner is neither changed nor deleted
+	other := &Reconciler{ControlClient: controlClient, ClusterName: "cluster-b", ReconcilerConfig: legacy.ReconcilerConfig}
+	other.ReconcilerConfig.OwnerID = "owner-2"
+	if err := other.ensureCertificate(context.TODO(), "test.example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := other.deleteUnusedCertificate(context.TODO(), "test.example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := controlClient.Get(context.TODO(), client.ObjectKeyFromObject(certificate), certificate); err != nil {
+		t.Errorf("expected the certificate of another owner to be kept: %v", err)
+	}
+	if certificate.GetLabels()[OwnerIDLabel] != "owner-1" {
+		t.Errorf("expected the owner ID 'owner-1' got '%v'", certificate.GetLabels()[OwnerIDLabel])
+	}
+}
+
+func Test_reconcileTLSNotSupported(t *testing.T) {
+	controlClient := testControlClient(t)
+	r := &Reconciler{
+		ControlClient:    controlClient,
+		WorkloadClient:   fake.NewClientBuilder().Build(),
+		ClusterName:      "cluster-a",
+		ReconcilerConfig: ReconcilerConfig{Namespace: "test-control", ManagedZone: "example.com", ClusterIssuer: "test-issuer"},
+	}
+	service := traffic.NewService(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "test-namespace", Name: "test-service"}})
+
+	pending, err := r.reconcileTLS(context.TODO(), service, []string{"test.example.com"})
+	if err != nil || pending {
+		t.Fatalf("expected no error and nothing pending got %v, %v", pending, err)
+	}
+	certificate := &unstructured.Unstructured{}
+	certificate.SetGroupVersionKind(CertificateGVK)
+	err = controlClient.Get(context.TODO(), client.ObjectKey{Namespace: "test-control", Name: "test.example.com"}, certificate)
+	if !k8serrors.IsNotFound(err) {
+		t.Errorf("expected no certificate for a kind without TLS, got: %v", err)
+	}
+}
+
+func Test_reconcileTLSFailedHost(t *testing.T) {
+	controlClient := testControlClient(t)
+	// a secret the controller does not manage is in the way of the first host
+	workloadClient := fake.NewClientBuilder().WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test-namespace", Name: "a.example.com-tls"},
+	}).Build()
+	r := &Reconciler{
+		ControlClient:    controlClient,
+		WorkloadClient:   workloadClient,
+		ClusterName:      "cluster-a",
+		ReconcilerConfig: ReconcilerConfig{Namespace: "test-control", ManagedZone: "example.com", ClusterIssuer: "test-issuer"},
+	}
+	hosts := []string{"a.example.com", "b.example.com"}
+	ingress := testIngress(hosts, "1.1.1.1")
+	for _, host := range hosts {
+		if err := controlClient.Create(context.TODO(), &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-control", Name: certificateSecretName(host)},
+			Type:       corev1.SecretTypeTLS,
+			Data:       map[string][]byte{corev1.TLSCertKey: []byte("cert"), corev1.TLSPrivateKeyKey: []byte("key")},
+		}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if _, err := r.reconcileTLS(context.TODO(), ingress, hosts); err == nil {
+		t.Errorf("expected an error for the first host")
+	}
+	if tlsHosts := ingress.GetAnnotations()[traffic.TLSHostsAnnotation]; tlsHosts != "b.example.com" {
+		t.Errorf("expected TLS hosts 'b.example.com' got '%v'", tlsHosts)
+	}
+	if len(ingress.Spec.TLS) != 1 || ingress.Spec.TLS[0].Hosts[0] != "b.example.com" {
+		t.Errorf("expected TLS for the second host only, got: %v", ingress.Spec.TLS)
+	}
+	if err := workloadClient.Get(context.TODO(), client.ObjectKey{Namespace: "test-namespace", Name: "b.example.com-tls"}, &corev1.Secret{}); err != nil {
+		t.Errorf("expected the secret of the second host to be copied: %v", err)
+	}
+}
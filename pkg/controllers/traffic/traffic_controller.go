@@ -18,47 +18,84 @@ package traffic
 
 import (
 	"context"
-	"fmt"
 
-	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
-	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
 )
 
+// ReconcilerConfig holds the settings shared by the reconcilers of all
+// workload clusters
+type ReconcilerConfig struct {
+	// Namespace of the control cluster the DNSRecords, domain verifications
+	// and certificates are managed in
+	Namespace string
+	// ManagedZone is the domain of the DNS zone hosts are generated under
+	ManagedZone string
+	// ClusterIssuer is the cert-manager cluster issuer certificates for the
+	// published hosts are requested from, no certificates are requested when
+	// empty
+	ClusterIssuer string
+	// OwnerID labels the DNSRecords, domain verifications and certificates
+	// managed by the controller, those of other owners are left alone
+	OwnerID string
+}
+
 // Reconciler reconciles a traffic object
 type Reconciler struct {
 	WorkloadClient client.Client
 	ControlClient  client.Client
+	// ClusterName identifies the workload cluster in the DNSRecord endpoints
+	ClusterName string
+	// ClusterLabels are matched by the cluster selectors of traffic policies
+	ClusterLabels map[string]string
+	// ClusterWeight is the weight of the endpoints of this cluster when no
+	// traffic policy sets one, DefaultWeight when empty
+	ClusterWeight    string
+	ReconcilerConfig ReconcilerConfig
 }
 
+//+kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;delete
+
+// Handle applies the host management policy to the traffic object and
+// publishes its managed and verified custom hosts, with TLS when a cluster
+// issuer is configured. Objects that are not opted in to management
+// are left as they are, apart from undoing any previous management.
 func (r *Reconciler) Handle(ctx context.Context, o runtime.Object) (ctrl.Result, error) {
 	_ = log.FromContext(ctx)
 
 	trafficAccessor := o.(traffic.Interface)
-	log.Log.Info("got traffic object", "kind", trafficAccessor.GetKind(), "name", trafficAccessor.GetName(), "namespace", trafficAccessor.GetNamespace())
-
-	if trafficAccessor.GetAnnotations() == nil {
-		return ctrl.Result{}, fmt.Errorf("expected dummy configmap annotation missing")
+	log.Log.V(1).Info("got traffic object", "kind", trafficAccessor.GetKind(), "name", trafficAccessor.GetName(), "namespace", trafficAccessor.GetNamespace())
+
+	if trafficAccessor.GetDeletionTimestamp() == nil {
+		var err error
+		if traffic.IsManaged(trafficAccessor) {
+			err = r.manageHost(ctx, trafficAccessor)
+		} else {
+			err = r.unmanageHost(trafficAccessor)
+		}
+		if err != nil {
+			return ctrl.Result{}, err
+		}
 	}
 
-	cmName := trafficAccessor.GetAnnotations()["configmap"]
-	cmField := trafficAccessor.GetAnnotations()["field"]
-
-	cm := &corev1.ConfigMap{}
-
-	err := r.WorkloadClient.Get(ctx, client.ObjectKey{Namespace: trafficAccessor.GetNamespace(), Name: cmName}, cm)
+	hosts, err := r.publishedHosts(ctx, trafficAccessor)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
-
-	value := cm.Data[cmField]
-
-	metadata.AddLabel(trafficAccessor, "dummy-configmap-value", value)
-
+	if err := r.reconcileDNS(ctx, trafficAccessor, hosts); err != nil {
+		return ctrl.Result{}, err
+	}
+	pending, err := r.reconcileTLS(ctx, trafficAccessor, hosts)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if pending {
+		return ctrl.Result{RequeueAfter: CertificateRequeueDelay}, nil
+	}
 	return ctrl.Result{}, nil
 }
@@ -0,0 +1,199 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package traffic
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	dnsAWS "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns/aws"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+const (
+	// GeoEndpointLabel is the geo of the clients a cluster endpoint serves,
+	// or the geo a layer endpoint routes to
+	GeoEndpointLabel = "kuadrant.io/geo"
+	// LayerEndpointLabel marks the endpoints that route the host to the
+	// cluster endpoints, rather than to a cluster
+	LayerEndpointLabel = "kuadrant.io/layer"
+	GeoLayer           = "geo"
+
+	// DefaultGeoSetIdentifier identifies the layer endpoint of the clients
+	// outside every mapped geo
+	DefaultGeoSetIdentifier = "default"
+)
+
+//+kubebuilder:rbac:groups=kuadrant.io,resources=trafficpolicies,verbs=get;list;watch
+
+// listPolicies returns the traffic policies of the control cluster.
+func (r *Reconciler) listPolicies(ctx context.Context) ([]v1.TrafficPolicy, error) {
+	policies := &v1.TrafficPolicyList{}
+	if err := r.ControlClient.List(ctx, policies, client.InNamespace(r.ReconcilerConfig.Namespace)); err != nil {
+		return nil, err
+	}
+	return policies.Items, nil
+}
+
+// policyFor returns the traffic policy that applies to the host of the
+// traffic object, nil when none does. When several policies apply the oldest
+// one is returned so every cluster picks the same policy.
+func policyFor(policies []v1.TrafficPolicy, t traffic.Interface, host string) *v1.TrafficPolicy {
+	var matches []v1.TrafficPolicy
+	for _, policy := range policies {
+		if appliesTo(policy, t, host) {
+			matches = append(matches, policy)
+		}
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].CreationTimestamp.Equal(&matches[j].CreationTimestamp) {
+			return matches[i].CreationTimestamp.Before(&matches[j].CreationTimestamp)
+		}
+		return matches[i].Name < matches[j].Name
+	})
+	return &matches[0]
+}
+
+func appliesTo(policy v1.TrafficPolicy, t traffic.Interface, host string) bool {
+	for _, policyHost := range policy.Spec.Hosts {
+		if strings.EqualFold(policyHost, host) {
+			return true
+		}
+	}
+	for _, ref := range policy.Spec.TargetRefs {
+		if ref.Kind == t.GetKind() && ref.Namespace == t.GetNamespace() && ref.Name == t.GetName() {
+			return true
+		}
+	}
+	return false
+}
+
+// selects returns whether this cluster is selected by name or labels.
+func (r *Reconciler) selects(selector v1.ClusterSelector) bool {
+	if slice.ContainsString(selector.Clusters, r.ClusterName) {
+		return true
+	}
+	if selector.Selector == nil {
+		return false
+	}
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector.Selector)
+	if err != nil {
+		log.Log.Error(err, "ignoring invalid cluster selector", "selector", selector.Selector)
+		return false
+	}
+	return labelSelector.Matches(labels.Set(r.ClusterLabels))
+}
+
+// applyPolicy sets the weight and geo of the endpoint of this cluster for the
+// host from the policy, falling back to the weight of the cluster. Endpoints in a geo are published under the host of
+// the geo, which the geo layer of the record routes the host to.
+func (r *Reconciler) applyPolicy(endpoint *v1.Endpoint, host string, policy *v1.TrafficPolicy) {
+	weight := DefaultWeight
+	if r.ClusterWeight != "" {
+		weight = r.ClusterWeight
+	}
+	if policy != nil {
+		for _, clusterWeight := range policy.Spec.Weights {
+			if r.selects(clusterWeight.ClusterSelector) {
+				weight = strconv.Itoa(clusterWeight.Weight)
+				break
+			}
+		}
+	}
+	endpoint.SetProviderSpecificProperty(dnsAWS.ProviderSpecificWeight, weight)
+
+	if policy == nil || policy.Spec.Geo == nil {
+		return
+	}
+	geo := policy.Spec.Geo.DefaultGeo
+	for _, mapping := range policy.Spec.Geo.Mappings {
+		if r.selects(mapping.ClusterSelector) {
+			geo = mapping.Geo
+			break
+		}
+	}
+	endpoint.Labels[GeoEndpointLabel] = geo
+	endpoint.DNSName = geoHost(geo, host)
+}
+
+// layerEndpoints returns the endpoints of the record for the host with the geo
+// layer regenerated from the cluster endpoints. The layer routes the clients
+// of each geo to the host of the geo, and every other client to the host of
+// the default geo, which is kept from the current layer when empty.
+func layerEndpoints(host string, endpoints []*v1.Endpoint, defaultGeo string) []*v1.Endpoint {
+	var clusterEndpoints []*v1.Endpoint
+	var geos []string
+	for _, endpoint := range endpoints {
+		if endpoint.Labels[LayerEndpointLabel] != "" {
+			if defaultGeo == "" && endpoint.SetIdentifier == DefaultGeoSetIdentifier {
+				defaultGeo = endpoint.Labels[GeoEndpointLabel]
+			}
+			continue
+		}
+		clusterEndpoints = append(clusterEndpoints, endpoint)
+		if geo := endpoint.Labels[GeoEndpointLabel]; geo != "" && !slice.ContainsString(geos, geo) {
+			geos = append(geos, geo)
+		}
+	}
+	if len(geos) == 0 {
+		return clusterEndpoints
+	}
+	sort.Strings(geos)
+	if !slice.ContainsString(geos, defaultGeo) {
+		defaultGeo = geos[0]
+	}
+
+	layer := []*v1.Endpoint{
+		layerEndpoint(host, defaultGeo, DefaultGeoSetIdentifier, dnsAWS.ProviderSpecificGeolocationCountryCode, "*"),
+	}
+	for _, geo := range geos {
+		layer = append(layer, layerEndpoint(host, geo, geo, dnsAWS.ProviderSpecificGeolocationContinentCode, geo))
+	}
+	return append(layer, clusterEndpoints...)
+}
+
+func layerEndpoint(host, geo, setIdentifier, property, value string) *v1.Endpoint {
+	return (&v1.Endpoint{
+		DNSName:       host,
+		Targets:       v1.Targets{geoHost(geo, host)},
+		RecordType:    string(v1.CNAMERecordType),
+		SetIdentifier: setIdentifier,
+		RecordTTL:     DefaultRecordTTL,
+		Labels: v1.Labels{
+			LayerEndpointLabel: GeoLayer,
+			GeoEndpointLabel:   geo,
+		},
+	}).WithProviderSpecific(property, value)
+}
+
+// geoHost returns the host the clusters serving the host in the geo are
+// published under.
+func geoHost(geo, host string) string {
+	return strings.ToLower(geo) + "." + host
+}
@@ -0,0 +1,175 @@
+package traffic
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	dnsAWS "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns/aws"
+)
+
+func Test_reconcileDNSWithTrafficPolicy(t *testing.T) {
+	controlClient := testControlClient(t)
+	config := ReconcilerConfig{Namespace: "test-control", ManagedZone: "example.com"}
+	clusterA := &Reconciler{ControlClient: controlClient, ClusterName: "cluster-a", ClusterLabels: map[string]string{"region": "eu"}, ReconcilerConfig: config}
+	clusterB := &Reconciler{ControlClient: controlClient, ClusterName: "cluster-b", ClusterLabels: map[string]string{"region": "us"}, ReconcilerConfig: config}
+
+	err := controlClient.Create(context.TODO(), &v1.TrafficPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-policy", Namespace: "test-control"},
+		Spec: v1.TrafficPolicySpec{
+			TargetRefs: []v1.TrafficTargetReference{{Kind: "Ingress", Namespace: "test-namespace", Name: "test-ingress"}},
+			Weights: []v1.ClusterWeight{
+				{ClusterSelector: v1.ClusterSelector{Clusters: []string{"cluster-a"}}, Weight: 90},
+				{ClusterSelector: v1.ClusterSelector{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"region": "us"}}}, Weight: 10},
+			},
+			Geo: &v1.GeoPolicy{
+				DefaultGeo: "NA",
+				Mappings: []v1.GeoMapping{
+					{ClusterSelector: v1.ClusterSelector{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"region": "eu"}}}, Geo: "EU"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := publish(clusterA, testIngress([]string{testHost}, "1.1.1.1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := publish(clusterB, testIngress([]string{testHost}, "2.2.2.2")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	type expectEndpoint struct {
+		dnsName  string
+		target   string
+		property string
+		value    string
+	}
+	expectEndpoints := func(expect map[string]expectEndpoint) {
+		t.Helper()
+		record := getRecord(t, controlClient, testHost)
+		if record == nil {
+			t.Fatalf("expected DNSRecord to exist")
+		}
+		if len(record.Spec.Endpoints) != len(expect) {
+			t.Fatalf("expected %d endpoints, got: %v", len(expect), record.Spec.Endpoints)
+		}
+		for _, endpoint := range record.Spec.Endpoints {
+			e, ok := expect[endpoint.SetIdentifier]
+			if !ok {
+				t.Errorf("unexpected endpoint '%v'", endpoint)
+				continue
+			}
+			if endpoint.DNSName != e.dnsName || endpoint.Targets[0] != e.target {
+				t.Errorf("expected endpoint %s to route '%v' to '%v', got: %v", endpoint.SetIdentifier, e.dnsName, e.target, endpoint)
+			}
+			if property, _ := endpoint.GetProviderSpecificProperty(e.property); property.Value != e.value {
+				t.Errorf("expected endpoint %s to have %s '%v', got: %v", endpoint.SetIdentifier, e.property, e.value, endpoint)
+			}
+		}
+	}
+
+	expectEndpoints(map[string]expectEndpoint{
+		DefaultGeoSetIdentifier:  {dnsName: testHost, target: "na." + testHost, property: dnsAWS.ProviderSpecificGeolocationCountryCode, value: "*"},
+		"EU":                     {dnsName: testHost, target: "eu." + testHost, property: dnsAWS.ProviderSpecificGeolocationContinentCode, value: "EU"},
+		"NA":                     {dnsName: testHost, target: "na." + testHost, property: dnsAWS.ProviderSpecificGeolocationContinentCode, value: "NA"},
+		"cluster-a/" + testOwner: {dnsName: "eu." + testHost, target: "1.1.1.1", property: dnsAWS.ProviderSpecificWeight, value: "90"},
+		"cluster-b/" + testOwner: {dnsName: "na." + testHost, target: "2.2.2.2", property: dnsAWS.ProviderSpecificWeight, value: "10"},
+	})
+
+	// the EU cluster stops serving the host, the default geo remains
+	unmanaged := testIngress([]string{testHost}, "1.1.1.1")
+	unmanaged.SetLabels(nil)
+	if err := publish(clusterA, unmanaged); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectEndpoints(map[string]expectEndpoint{
+		DefaultGeoSetIdentifier:  {dnsName: testHost, target: "na." + testHost, property: dnsAWS.ProviderSpecificGeolocationCountryCode, value: "*"},
+		"NA":                     {dnsName: testHost, target: "na." + testHost, property: dnsAWS.ProviderSpecificGeolocationContinentCode, value: "NA"},
+		"cluster-b/" + testOwner: {dnsName: "na." + testHost, target: "2.2.2.2", property: dnsAWS.ProviderSpecificWeight, value: "10"},
+	})
+}
+
+func Test_applyPolicy(t *testing.T) {
+	euSelector := v1.ClusterSelector{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"region": "eu"}}}
+
+	tests := []struct {
+		name          string
+		clusterWeight string
+		policy        *v1.TrafficPolicy
+		weight        string
+		dnsName       string
+	}{
+		{
+			name:    "no policy",
+			weight:  DefaultWeight,
+			dnsName: "test.example.com",
+		},
+		{
+			name:          "cluster weight without policy",
+			clusterWeight: "30",
+			weight:        "30",
+			dnsName:       "test.example.com",
+		},
+		{
+			name:          "policy weight overrides cluster weight",
+			clusterWeight: "30",
+			policy: &v1.TrafficPolicy{Spec: v1.TrafficPolicySpec{
+				Weights: []v1.ClusterWeight{{ClusterSelector: euSelector, Weight: 90}},
+			}},
+			weight:  "90",
+			dnsName: "test.example.com",
+		},
+		{
+			name: "cluster not weighted",
+			policy: &v1.TrafficPolicy{Spec: v1.TrafficPolicySpec{
+				Weights: []v1.ClusterWeight{{ClusterSelector: v1.ClusterSelector{Clusters: []string{"cluster-b"}}, Weight: 10}},
+			}},
+			weight:  DefaultWeight,
+			dnsName: "test.example.com",
+		},
+		{
+			name: "first matching weight wins",
+			policy: &v1.TrafficPolicy{Spec: v1.TrafficPolicySpec{
+				Weights: []v1.ClusterWeight{{ClusterSelector: euSelector, Weight: 0}, {ClusterSelector: v1.ClusterSelector{Clusters: []string{"cluster-a"}}, Weight: 50}},
+			}},
+			weight:  "0",
+			dnsName: "test.example.com",
+		},
+		{
+			name: "unmapped cluster is in the default geo",
+			policy: &v1.TrafficPolicy{Spec: v1.TrafficPolicySpec{
+				Geo: &v1.GeoPolicy{DefaultGeo: "NA", Mappings: []v1.GeoMapping{{ClusterSelector: v1.ClusterSelector{Clusters: []string{"cluster-b"}}, Geo: "AS"}}},
+			}},
+			weight:  DefaultWeight,
+			dnsName: "na.test.example.com",
+		},
+		{
+			name: "mapped cluster",
+			policy: &v1.TrafficPolicy{Spec: v1.TrafficPolicySpec{
+				Geo: &v1.GeoPolicy{DefaultGeo: "NA", Mappings: []v1.GeoMapping{{ClusterSelector: euSelector, Geo: "EU"}}},
+			}},
+			weight:  DefaultWeight,
+			dnsName: "eu.test.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Reconciler{ClusterName: "cluster-a", ClusterLabels: map[string]string{"region": "eu"}, ClusterWeight: tt.clusterWeight}
+			endpoint := r.endpointFor([]string{"1.1.1.1"}, "Ingress/test-namespace/test-ingress")
+			endpoint.DNSName = "test.example.com"
+			r.applyPolicy(endpoint, "test.example.com", tt.policy)
+			if weight, _ := endpoint.GetProviderSpecificProperty(dnsAWS.ProviderSpecificWeight); weight.Value != tt.weight {
+				t.Errorf("expected weight '%v' got '%v'", tt.weight, weight.Value)
+			}
+			if endpoint.DNSName != tt.dnsName {
+				t.Errorf("expected DNS name '%v' got '%v'", tt.dnsName, endpoint.DNSName)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package traffic
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+const (
+	// VerificationOwnerLabel holds a hash of the owner of a verification, so
+	// the verifications of an owner can be listed
+	VerificationOwnerLabel = "kuadrant.io/verification-owner"
+
+	// tokenLength is the number of random bytes in a verification token
+	tokenLength = 16
+)
+
+// publishedHosts returns the hosts of the traffic object that are published.
+// A host under the managed zone is only published when it is the host
+// generated for the object, any other host is a custom host that is only
+// published once the object has verified it owns the domain. The TXT records still needed to verify custom hosts are listed
+// in the verification annotation of the object. The verifications of custom
+// hosts the object no longer requests are deleted.
+func (r *Reconciler) publishedHosts(ctx context.Context, t traffic.Interface) ([]string, error) {
+	owner := r.verificationOwner(t)
+	if t.GetDeletionTimestamp() != nil || !traffic.IsManaged(t) {
+		return nil, r.deleteUnusedVerifications(ctx, owner, nil)
+	}
+
+	var hosts, customHosts []string
+	pending := map[string]string{}
+	for _, host := range t.GetHosts() {
+		host = strings.ToLower(host)
+		if host == "" || strings.Contains(host, "*") || slice.ContainsString(hosts, host) {
+			continue
+		}
+		if r.inManagedZone(host) {
+			generated, err := r.isGeneratedHost(ctx, t, host)
+			if err != nil {
+				return nil, err
+			}
+			if !generated {
+				log.Log.Info("skipping host in the managed zone that was not generated for the object", "host", host, "owner", ownerKey(t))
+				continue
+			}
+			hosts = append(hosts, host)
+			continue
+		}
+		customHosts = append(customHosts, host)
+		verification, err := r.ensureVerification(ctx, host, owner)
+		if err != nil {
+			return nil, err
+		}
+		if !verification.Status.Verified {
+			pending[verification.RecordName()] = verification.Spec.Token
+			continue
+		}
+		hosts = append(hosts, host)
+	}
+	if err := r.deleteUnusedVerifications(ctx, owner, customHosts); err != nil {
+		return nil, err
+	}
+
+	if len(pending) == 0 {
+		metadata.RemoveAnnotation(t, traffic.VerificationAnnotation)
+		return hosts, nil
+	}
+	pendingJSON, err := json.Marshal(pending)
+	if err != nil {
+		return nil, err
+	}
+	metadata.AddAnnotation(t, traffic.VerificationAnnotation, string(pendingJSON))
+	return hosts, nil
+}
+
+// ensureVerification returns the verification of the host for the owner,
+// issuing a new token if the owner has not requested the host before.
+func (r *Reconciler) ensureVerification(ctx context.Context, host, owner string) (*v1.DomainVerification, error) {
+	verification := &v1.DomainVerification{}
+	key := client.ObjectKey{Namespace: r.ReconcilerConfig.Namespace, Name: verificationName(host, owner)}
+	err := r.ControlClient.Get(ctx, key, verification)
+	if err == nil && !IsOwned(verification, r.ReconcilerConfig.OwnerID) {
+		return nil, fmt.Errorf("DomainVerification %s/%s already exists and is owned by %q", key.Namespace, key.Name, verification.Labels[OwnerIDLabel])
+	}
+	if err == nil {
+		return verification, nil
+	}
+	if !k8serrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	verification = &v1.DomainVerification{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+			Labels:    r.managedLabels(),
+		},
+		Spec: v1.DomainVerificationSpec{
+			Domain: host,
+			Owner:  owner,
+			Token:  token,
+		},
+	}
+	verification.Labels[VerificationOwnerLabel] = ownerHash(owner)
+	err = r.ControlClient.Create(ctx, verification)
+	if k8serrors.IsAlreadyExists(err) {
+		// another cluster issued the token first
+		err = r.ControlClient.Get(ctx, key, verification)
+	} else if err == nil {
+		log.Log.Info("requested domain verification", "domain", host, "owner", owner, "record", verification.RecordName())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return verification, nil
+}
+
+// deleteUnusedVerifications deletes the verifications of the owner for the
+// hosts it no longer requests.
+func (r *Reconciler) deleteUnusedVerifications(ctx context.Context, owner string, hosts []string) error {
+	verifications := &v1.DomainVerificationList{}
+	if err := r.ControlClient.List(ctx, verifications, client.InNamespace(r.ReconcilerConfig.Namespace), client.MatchingLabels{
+		ManagedByLabel:         ManagedByLabelValue,
+		VerificationOwnerLabel: ownerHash(owner),
+	}); err != nil {
+		return err
+	}
+	for i := range verifications.Items {
+		verification := &verifications.Items[i]
+		if verification.Spec.Owner != owner || slice.ContainsString(hosts, verification.Spec.Domain) {
+			continue
+		}
+		if err := r.ControlClient.Delete(ctx, verification); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		log.Log.Info("deleted unused domain verification", "domain", verification.Spec.Domain, "owner", owner)
+	}
+	return nil
+}
+
+// verificationOwner returns the owner of the verifications of the traffic
+// object. Objects with the same name on different clusters are different
+// owners, so a domain verified on one cluster isn't verified on another.
+func (r *Reconciler) verificationOwner(t traffic.Interface) string {
+	return fmt.Sprintf("%s/%s", r.ClusterName, ownerKey(t))
+}
+
+// verificationName returns the name of the verification of the host for the
+// owner. Each owner verifies a host separately, so one owner cannot publish a
+// host verified by another.
+func verificationName(host, owner string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%s", owner, host)))
+	return "dv-" + hex.EncodeToString(sum[:])[:16]
+}
+
+// ownerHash returns the value of the owner label of the verifications of the
+// owner, which may not fit in a label value.
+func ownerHash(owner string) string {
+	sum := sha256.Sum256([]byte(owner))
+	return hex.EncodeToString(sum[:])[:32]
+}
+
+func newToken() (string, error) {
+	token := make([]byte, tokenLength)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate verification token: %w", err)
+	}
+	return hex.EncodeToString(token), nil
+}
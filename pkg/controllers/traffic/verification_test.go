@@ -0,0 +1,151 @@
+package traffic
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+func Test_publishedHosts(t *testing.T) {
+	controlClient := testControlClient(t)
+	r := &Reconciler{
+		ControlClient:    controlClient,
+		ClusterName:      "cluster-a",
+		ReconcilerConfig: ReconcilerConfig{Namespace: "test-control", ManagedZone: "example.com"},
+	}
+	ingress := testIngress([]string{testHost, "App.Team.com", "*.team.com"}, "1.1.1.1")
+
+	// the custom host is held back until it is verified
+	hosts, err := r.publishedHosts(context.TODO(), ingress)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hosts) != 1 || hosts[0] != testHost {
+		t.Fatalf("expected only the managed zone host to be published, got: %v", hosts)
+	}
+	pending := map[string]string{}
+	if err := json.Unmarshal([]byte(ingress.GetAnnotations()[traffic.VerificationAnnotation]), &pending); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	verification := &v1.DomainVerification{}
+	key := client.ObjectKey{Namespace: "test-control", Name: verificationName("app.team.com", r.verificationOwner(ingress))}
+	if err := controlClient.Get(context.TODO(), key, verification); err != nil {
+		t.Fatalf("expected a verification for the custom host: %v", err)
+	}
+	if token := pending["_mctc-verify.app.team.com"]; token == "" || token != verification.Spec.Token {
+		t.Fatalf("expected the pending token '%v' got '%v'", verification.Spec.Token, token)
+	}
+
+	// another object asking for the same host gets its own verification
+	other := testIngress([]string{"app.team.com"}, "2.2.2.2")
+	other.SetName("other-ingress")
+	if _, err := r.publishedHosts(context.TODO(), other); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if verificationName("app.team.com", r.verificationOwner(other)) == key.Name {
+		t.Fatalf("expected verifications to be per owner")
+	}
+
+	// the same object on another cluster gets its own verification
+	clusterB := &Reconciler{ControlClient: controlClient, ClusterName: "cluster-b", ReconcilerConfig: r.ReconcilerConfig}
+	if verificationName("app.team.com", clusterB.verificationOwner(ingress)) == key.Name {
+		t.Fatalf("expected verifications to be per cluster")
+	}
+
+	// once verified the custom host is published for its owner only
+	verification.Status.Verified = true
+	if err := controlClient.Status().Update(context.TODO(), verification); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	hosts, err = r.publishedHosts(context.TODO(), ingress)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hosts) != 2 || hosts[1] != "app.team.com" {
+		t.Fatalf("expected the verified host to be published, got: %v", hosts)
+	}
+	if _, ok := ingress.GetAnnotations()[traffic.VerificationAnnotation]; ok {
+		t.Errorf("expected the verification annotation to be removed once all hosts are verified")
+	}
+	hosts, err = r.publishedHosts(context.TODO(), other)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hosts) != 0 {
+		t.Errorf("expected the host to stay unpublished for another owner, got: %v", hosts)
+	}
+
+	hostsB, err := clusterB.publishedHosts(context.TODO(), testIngress([]string{"app.team.com"}, "3.3.3.3"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hostsB) != 0 {
+		t.Errorf("expected the host verified on cluster-a to stay unpublished on cluster-b, got: %v", hostsB)
+	}
+
+	// the verification is deleted once the object no longer requests the host
+	if _, err := r.publishedHosts(context.TODO(), testIngress([]string{testHost}, "1.1.1.1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := controlClient.Get(context.TODO(), key, verification); !k8serrors.IsNotFound(err) {
+		t.Errorf("expected the unused verification to be deleted got '%v'", err)
+	}
+
+	// and when the object is deleted
+	deleted := testIngress([]string{"app.team.com"}, "2.2.2.2")
+	deleted.SetName("other-ingress")
+	now := metav1.Now()
+	deleted.SetDeletionTimestamp(&now)
+	if _, err := r.publishedHosts(context.TODO(), deleted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	otherKey := client.ObjectKey{Namespace: "test-control", Name: verificationName("app.team.com", r.verificationOwner(deleted))}
+	if err := controlClient.Get(context.TODO(), otherKey, &v1.DomainVerification{}); !k8serrors.IsNotFound(err) {
+		t.Errorf("expected the verification of the deleted object to be deleted got '%v'", err)
+	}
+}
+
+func Test_publishedHostsWithOwnerID(t *testing.T) {
+	controlClient := testControlClient(t)
+	legacy := &Reconciler{
+		ControlClient:    controlClient,
+		ClusterName:      "cluster-a",
+		ReconcilerConfig: ReconcilerConfig{Namespace: "test-control", ManagedZone: "example.com"},
+	}
+	ingress := testIngress([]string{"app.team.com"}, "1.1.1.1")
+	if _, err := legacy.publishedHosts(context.TODO(), ingress); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	verification := &v1.DomainVerification{}
+	key := client.ObjectKey{Namespace: "test-control", Name: verificationName("app.team.com", legacy.verificationOwner(ingress))}
+	if err := controlClient.Get(context.TODO(), key, verification); err != nil {
+		t.Fatalf("expected a verification for the custom host: %v", err)
+	}
+	if _, ok := verification.Labels[OwnerIDLabel]; ok {
+		t.Errorf("expected no owner ID without one configured, got: %v", verification.Labels)
+	}
+
+	// the verification issued without an owner ID is not owned
+	owned := &Reconciler{
+		ControlClient:    controlClient,
+		ClusterName:      "cluster-a",
+		ReconcilerConfig: ReconcilerConfig{Namespace: "test-control", ManagedZone: "example.com", OwnerID: "owner-1"},
+	}
+	if _, err := owned.publishedHosts(context.TODO(), ingress); err == nil {
+		t.Errorf("expected an error for the verification without an owner ID")
+	}
+	kept := &v1.DomainVerification{}
+	if err := controlClient.Get(context.TODO(), key, kept); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := kept.Labels[OwnerIDLabel]; ok {
+		t.Errorf("expected the verification not to be labelled with the owner ID, got: %v", kept.Labels)
+	}
+}
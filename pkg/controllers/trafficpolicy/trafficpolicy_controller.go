@@ -0,0 +1,151 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trafficpolicy
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	trafficController "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/traffic"
+)
+
+// TrafficRequeuer handles traffic objects of the workload clusters again
+type TrafficRequeuer interface {
+	// Requeue handles the traffic object of the kind again on the workload
+	// cluster, or on every workload cluster when the cluster is empty
+	Requeue(cluster, kind, namespace, name string)
+}
+
+// TrafficPolicyReconciler handles the traffic a TrafficPolicy applies to
+// again when the policy changes, so the policy is compiled into the DNSRecords
+// of its hosts. The traffic the previous version of the policy applied to is
+// handled again too, so hosts the policy no longer applies to lose it.
+type TrafficPolicyReconciler struct {
+	client.Client
+	Scheme  *runtime.Scheme
+	Traffic TrafficRequeuer
+	// Namespace of the control cluster the policies and DNSRecords are in
+	Namespace string
+
+	lock sync.Mutex
+	// applied holds the last reconciled spec of each policy
+	applied map[types.NamespacedName]v1.TrafficPolicySpec
+}
+
+//+kubebuilder:rbac:groups=kuadrant.io,resources=trafficpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=kuadrant.io,resources=dnsrecords,verbs=get;list;watch
+
+func (r *TrafficPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	_ = log.FromContext(ctx)
+
+	var specs []v1.TrafficPolicySpec
+	policy := &v1.TrafficPolicy{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: req.Name}, policy)
+	if client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, err
+	}
+	found := err == nil
+	if found {
+		specs = append(specs, policy.Spec)
+	}
+
+	r.lock.Lock()
+	if r.applied == nil {
+		r.applied = map[types.NamespacedName]v1.TrafficPolicySpec{}
+	}
+	if previous, ok := r.applied[req.NamespacedName]; ok {
+		specs = append(specs, previous)
+	}
+	r.lock.Unlock()
+
+	targets := map[target]bool{}
+	for _, spec := range specs {
+		if err := r.addTargets(ctx, spec, targets); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	for t := range targets {
+		r.Traffic.Requeue(t.cluster, t.kind, t.namespace, t.name)
+	}
+	log.Log.V(1).Info("requeued traffic of policy", "policy", req.NamespacedName, "objects", len(targets))
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if found {
+		r.applied[req.NamespacedName] = policy.Spec
+	} else {
+		delete(r.applied, req.NamespacedName)
+	}
+	return ctrl.Result{}, nil
+}
+
+// target is a traffic object on a workload cluster, or on every workload
+// cluster when the cluster is empty
+type target struct {
+	cluster   string
+	kind      string
+	namespace string
+	name      string
+}
+
+// addTargets adds the traffic the policy spec applies to: the objects with an
+// endpoint in the DNSRecords of the hosts of the policy and the objects it
+// references.
+func (r *TrafficPolicyReconciler) addTargets(ctx context.Context, spec v1.TrafficPolicySpec, targets map[target]bool) error {
+	for _, host := range spec.Hosts {
+		record := &v1.DNSRecord{}
+		err := r.Client.Get(ctx, client.ObjectKey{Namespace: r.Namespace, Name: strings.ToLower(host)}, record)
+		if err != nil {
+			if client.IgnoreNotFound(err) != nil {
+				return err
+			}
+			continue
+		}
+		for _, endpoint := range record.Spec.Endpoints {
+			kind, namespace, name := trafficController.EndpointOwner(endpoint)
+			cluster := endpoint.Labels[trafficController.ClusterEndpointLabel]
+			if kind == "" || cluster == "" {
+				continue
+			}
+			targets[target{cluster: cluster, kind: kind, namespace: namespace, name: name}] = true
+		}
+	}
+	for _, ref := range spec.TargetRefs {
+		targets[target{kind: ref.Kind, namespace: ref.Namespace, name: ref.Name}] = true
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *TrafficPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.TrafficPolicy{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		WithEventFilter(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return obj.GetNamespace() == r.Namespace
+		})).
+		Complete(r)
+}
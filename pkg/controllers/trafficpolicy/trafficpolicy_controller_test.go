@@ -0,0 +1,103 @@
+package trafficpolicy
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	trafficController "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/traffic"
+)
+
+type testRequeuer struct {
+	requeued []string
+}
+
+func (r *testRequeuer) Requeue(cluster, kind, namespace, name string) {
+	r.requeued = append(r.requeued, cluster+"/"+kind+"/"+namespace+"/"+name)
+}
+
+func (r *testRequeuer) take() []string {
+	requeued := r.requeued
+	r.requeued = nil
+	sort.Strings(requeued)
+	return requeued
+}
+
+func testRecord(host string, owners map[string]string) *v1.DNSRecord {
+	record := &v1.DNSRecord{ObjectMeta: metav1.ObjectMeta{Namespace: "test-control", Name: host}}
+	for cluster, owner := range owners {
+		record.Spec.Endpoints = append(record.Spec.Endpoints, &v1.Endpoint{
+			DNSName: host,
+			Labels: v1.Labels{
+				trafficController.ClusterEndpointLabel: cluster,
+				trafficController.OwnerEndpointLabel:   owner,
+			},
+		})
+	}
+	return record
+}
+
+func TestTrafficPolicyReconciler_Reconcile(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := v1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	policy := &v1.TrafficPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test-control", Name: "test-policy"},
+		Spec: v1.TrafficPolicySpec{
+			Hosts:      []string{"App.example.com"},
+			TargetRefs: []v1.TrafficTargetReference{{Kind: "Service", Namespace: "test-namespace", Name: "test-service"}},
+		},
+	}
+	controlClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		policy,
+		testRecord("app.example.com", map[string]string{"cluster-a": "Ingress/test-namespace/test-ingress"}),
+		testRecord("api.example.com", map[string]string{"cluster-b": "Ingress/test-namespace/api"}),
+	).Build()
+	requeuer := &testRequeuer{}
+	r := &TrafficPolicyReconciler{Client: controlClient, Traffic: requeuer, Namespace: "test-control"}
+	key := client.ObjectKeyFromObject(policy)
+	reconcile := func() {
+		t.Helper()
+		if _, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// the objects serving the hosts of the policy and the referenced objects
+	reconcile()
+	expect := []string{"/Service/test-namespace/test-service", "cluster-a/Ingress/test-namespace/test-ingress"}
+	if requeued := requeuer.take(); !reflect.DeepEqual(requeued, expect) {
+		t.Errorf("expected requeued '%v' got '%v'", expect, requeued)
+	}
+
+	// the policy moves to another host, the objects of the previous host lose it
+	policy.Spec.Hosts = []string{"api.example.com"}
+	policy.Spec.TargetRefs = nil
+	if err := controlClient.Update(context.TODO(), policy); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reconcile()
+	expect = []string{"/Service/test-namespace/test-service", "cluster-a/Ingress/test-namespace/test-ingress", "cluster-b/Ingress/test-namespace/api"}
+	if requeued := requeuer.take(); !reflect.DeepEqual(requeued, expect) {
+		t.Errorf("expected requeued '%v' got '%v'", expect, requeued)
+	}
+
+	// the policy is deleted
+	if err := controlClient.Delete(context.TODO(), policy); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reconcile()
+	expect = []string{"cluster-b/Ingress/test-namespace/api"}
+	if requeued := requeuer.take(); !reflect.DeepEqual(requeued, expect) {
+		t.Errorf("expected requeued '%v' got '%v'", expect, requeued)
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package aws
 
 import (
+	"context"
 	"strconv"
 	"time"
 
@@ -24,13 +25,20 @@ import (
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/route53"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/tracing"
 )
 
 type InstrumentedRoute53 struct {
 	route53 *route53.Route53
 }
 
-func observe(operation string, f func() error) {
+// observe instruments a Route53 call. The operation is the label of the call
+// in the Route53 metrics, calls moved to their context-aware variants keep
+// the label of the call they replaced.
+func observe(ctx context.Context, operation string, f func() error) {
+	_, span := tracing.Tracer().Start(ctx, "route53."+operation)
 	start := time.Now()
 	route53RequestCount.WithLabelValues(operation).Inc()
 	defer route53RequestCount.WithLabelValues(operation).Dec()
@@ -51,34 +59,36 @@ func observe(operation string, f func() error) {
 	}
 	route53RequestDuration.WithLabelValues(operation, code).Observe(duration)
 	route53RequestTotal.WithLabelValues(operation, code).Inc()
+	span.SetAttributes(attribute.String("aws.route53.code", code))
+	tracing.End(span, err)
 }
 
-func (c *InstrumentedRoute53) ListHostedZones(input *route53.ListHostedZonesInput) (output *route53.ListHostedZonesOutput, err error) {
-	observe("ListHostedZones", func() error {
-		output, err = c.route53.ListHostedZones(input)
+func (c *InstrumentedRoute53) ListHostedZonesWithContext(ctx aws.Context, input *route53.ListHostedZonesInput, opts ...request.Option) (output *route53.ListHostedZonesOutput, err error) {
+	observe(ctx, "ListHostedZones", func() error {
+		output, err = c.route53.ListHostedZonesWithContext(ctx, input, opts...)
 		return err
 	})
 	return
 }
 
-func (c *InstrumentedRoute53) ChangeResourceRecordSets(input *route53.ChangeResourceRecordSetsInput) (output *route53.ChangeResourceRecordSetsOutput, err error) {
-	observe("ChangeResourceRecordSets", func() error {
-		output, err = c.route53.ChangeResourceRecordSets(input)
+func (c *InstrumentedRoute53) ChangeResourceRecordSetsWithContext(ctx aws.Context, input *route53.ChangeResourceRecordSetsInput, opts ...request.Option) (output *route53.ChangeResourceRecordSetsOutput, err error) {
+	observe(ctx, "ChangeResourceRecordSets", func() error {
+		output, err = c.route53.ChangeResourceRecordSetsWithContext(ctx, input, opts...)
 		return err
 	})
 	return
 }
 
-func (c *InstrumentedRoute53) CreateHealthCheck(input *route53.CreateHealthCheckInput) (output *route53.CreateHealthCheckOutput, err error) {
-	observe("CreateHealthCheck", func() error {
-		output, err = c.route53.CreateHealthCheck(input)
+func (c *InstrumentedRoute53) CreateHealthCheckWithContext(ctx aws.Context, input *route53.CreateHealthCheckInput, opts ...request.Option) (output *route53.CreateHealthCheckOutput, err error) {
+	observe(ctx, "CreateHealthCheck", func() error {
+		output, err = c.route53.CreateHealthCheckWithContext(ctx, input, opts...)
 		return err
 	})
 	return
 }
 
 func (c *InstrumentedRoute53) GetHealthCheckWithContext(ctx aws.Context, input *route53.GetHealthCheckInput, opts ...request.Option) (output *route53.GetHealthCheckOutput, err error) {
-	observe("GetHealthCheckWithContext", func() error {
+	observe(ctx, "GetHealthCheckWithContext", func() error {
 		output, err = c.route53.GetHealthCheckWithContext(ctx, input, opts...)
 		return err
 	})
@@ -86,7 +96,7 @@ func (c *InstrumentedRoute53) GetHealthCheckWithContext(ctx aws.Context, input *
 }
 
 func (c *InstrumentedRoute53) UpdateHealthCheckWithContext(ctx aws.Context, input *route53.UpdateHealthCheckInput, opts ...request.Option) (output *route53.UpdateHealthCheckOutput, err error) {
-	observe("UpdateHealthCheckWithContext", func() error {
+	observe(ctx, "UpdateHealthCheckWithContext", func() error {
 		output, err = c.route53.UpdateHealthCheckWithContext(ctx, input, opts...)
 		return err
 	})
@@ -94,7 +104,7 @@ func (c *InstrumentedRoute53) UpdateHealthCheckWithContext(ctx aws.Context, inpu
 }
 
 func (c *InstrumentedRoute53) DeleteHealthCheckWithContext(ctx aws.Context, input *route53.DeleteHealthCheckInput, opts ...request.Option) (output *route53.DeleteHealthCheckOutput, err error) {
-	observe("DeleteHealthCheckWithContext", func() error {
+	observe(ctx, "DeleteHealthCheckWithContext", func() error {
 		output, err = c.route53.DeleteHealthCheckWithContext(ctx, input, opts...)
 		return err
 	})
@@ -102,7 +112,7 @@ func (c *InstrumentedRoute53) DeleteHealthCheckWithContext(ctx aws.Context, inpu
 }
 
 func (c *InstrumentedRoute53) ChangeTagsForResourceWithContext(ctx aws.Context, input *route53.ChangeTagsForResourceInput, opts ...request.Option) (output *route53.ChangeTagsForResourceOutput, err error) {
-	observe("ChangeTagsForResourceWithContext", func() error {
+	observe(ctx, "ChangeTagsForResourceWithContext", func() error {
 		output, err = c.route53.ChangeTagsForResourceWithContext(ctx, input, opts...)
 		return err
 	})
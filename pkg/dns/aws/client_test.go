@@ -0,0 +1,64 @@
+package aws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestInstrumentedRoute53OperationLabels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	sess := session.Must(session.NewSession(&aws.Config{
+		Endpoint:    aws.String(server.URL),
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		MaxRetries:  aws.Int(0),
+	}))
+	c := &InstrumentedRoute53{route53: route53.New(sess)}
+
+	// the context-aware calls keep the labels of the calls they replaced
+	calls := map[string]func() error{
+		"ListHostedZones": func() error {
+			_, err := c.ListHostedZonesWithContext(context.TODO(), &route53.ListHostedZonesInput{})
+			return err
+		},
+		"ChangeResourceRecordSets": func() error {
+			_, err := c.ChangeResourceRecordSetsWithContext(context.TODO(), &route53.ChangeResourceRecordSetsInput{
+				HostedZoneId: aws.String("zone"),
+				ChangeBatch: &route53.ChangeBatch{Changes: []*route53.Change{{
+					Action: aws.String(route53.ChangeActionUpsert),
+					ResourceRecordSet: &route53.ResourceRecordSet{
+						Name: aws.String("test.example.com"),
+						Type: aws.String(route53.RRTypeA),
+					},
+				}}},
+			})
+			return err
+		},
+		"CreateHealthCheck": func() error {
+			_, err := c.CreateHealthCheckWithContext(context.TODO(), &route53.CreateHealthCheckInput{
+				CallerReference:   aws.String("reference"),
+				HealthCheckConfig: &route53.HealthCheckConfig{Type: aws.String("HTTP")},
+			})
+			return err
+		},
+	}
+	for operation, call := range calls {
+		if err := call(); err == nil {
+			t.Errorf("expected %v to fail", operation)
+		}
+		if count := testutil.ToFloat64(route53RequestTotal.WithLabelValues(operation, "500")); count != 1 {
+			t.Errorf("expected 1 request for operation '%v' got %v", operation, count)
+		}
+	}
+}
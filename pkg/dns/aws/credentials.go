@@ -0,0 +1,107 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+)
+
+const (
+	// The keys of the credentials in a credentials secret
+	AccessKeyIDKey          = "AWS_ACCESS_KEY_ID"
+	SecretAccessKeyKey      = "AWS_SECRET_ACCESS_KEY"
+	SessionTokenKey         = "AWS_SESSION_TOKEN"
+	RoleARNKey              = "AWS_ROLE_ARN"
+	ExternalIDKey           = "AWS_EXTERNAL_ID"
+	WebIdentityTokenFileKey = "AWS_WEB_IDENTITY_TOKEN_FILE"
+	RegionKey               = "AWS_REGION"
+
+	// roleSessionName is the name of the sessions of the assumed roles
+	roleSessionName = "multi-cluster-traffic-controller"
+)
+
+// Credentials of an AWS account. The role is assumed with the static keys
+// when both are set, or with the web identity token when a token file is set.
+type Credentials struct {
+	AccessKeyID          string
+	SecretAccessKey      string
+	SessionToken         string
+	RoleARN              string
+	ExternalID           string
+	WebIdentityTokenFile string
+}
+
+// CredentialsFromSecret reads the credentials and region from the data of a
+// credentials secret.
+func CredentialsFromSecret(data map[string][]byte) (*Credentials, string, error) {
+	c := &Credentials{
+		AccessKeyID:          string(data[AccessKeyIDKey]),
+		SecretAccessKey:      string(data[SecretAccessKeyKey]),
+		SessionToken:         string(data[SessionTokenKey]),
+		RoleARN:              string(data[RoleARNKey]),
+		ExternalID:           string(data[ExternalIDKey]),
+		WebIdentityTokenFile: string(data[WebIdentityTokenFileKey]),
+	}
+	return c, string(data[RegionKey]), c.Validate()
+}
+
+// Validate returns an error for incomplete credentials.
+func (c *Credentials) Validate() error {
+	if (c.AccessKeyID == "") != (c.SecretAccessKey == "") {
+		return fmt.Errorf("both %s and %s are required for static credentials", AccessKeyIDKey, SecretAccessKeyKey)
+	}
+	if c.WebIdentityTokenFile != "" && c.RoleARN == "" {
+		return fmt.Errorf("%s is required with %s", RoleARNKey, WebIdentityTokenFileKey)
+	}
+	if c.ExternalID != "" && c.RoleARN == "" {
+		return fmt.Errorf("%s is required with %s", RoleARNKey, ExternalIDKey)
+	}
+	if c.AccessKeyID == "" && c.RoleARN == "" {
+		return fmt.Errorf("either %s or %s is required", AccessKeyIDKey, RoleARNKey)
+	}
+	return nil
+}
+
+// static returns the static credentials, nil when the keys aren't set.
+func (c *Credentials) static() *credentials.Credentials {
+	if c.AccessKeyID == "" {
+		return nil
+	}
+	return credentials.NewStaticCredentials(c.AccessKeyID, c.SecretAccessKey, c.SessionToken)
+}
+
+// role returns the credentials of the assumed role, nil when no role is set.
+// The role is assumed through the session.
+func (c *Credentials) role(session client.ConfigProvider) *credentials.Credentials {
+	switch {
+	case c.WebIdentityTokenFile != "":
+		return stscreds.NewWebIdentityCredentials(session, c.RoleARN, roleSessionName, c.WebIdentityTokenFile)
+	case c.RoleARN != "":
+		return stscreds.NewCredentials(session, c.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+			p.RoleSessionName = roleSessionName
+			if c.ExternalID != "" {
+				p.ExternalID = aws.String(c.ExternalID)
+			}
+		})
+	}
+	return nil
+}
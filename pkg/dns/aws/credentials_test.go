@@ -0,0 +1,59 @@
+package aws
+
+import (
+	"testing"
+)
+
+func TestCredentialsFromSecret(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      map[string][]byte
+		expectErr bool
+	}{
+		{
+			name: "static keys",
+			data: map[string][]byte{AccessKeyIDKey: []byte("id"), SecretAccessKeyKey: []byte("secret")},
+		},
+		{
+			name: "assumed role with external ID",
+			data: map[string][]byte{
+				AccessKeyIDKey:     []byte("id"),
+				SecretAccessKeyKey: []byte("secret"),
+				RoleARNKey:         []byte("arn:aws:iam::123456789012:role/dns"),
+				ExternalIDKey:      []byte("external"),
+			},
+		},
+		{
+			name: "web identity",
+			data: map[string][]byte{RoleARNKey: []byte("arn:aws:iam::123456789012:role/dns"), WebIdentityTokenFileKey: []byte("/var/run/token")},
+		},
+		{
+			name:      "missing secret key",
+			data:      map[string][]byte{AccessKeyIDKey: []byte("id")},
+			expectErr: true,
+		},
+		{
+			name:      "web identity without role",
+			data:      map[string][]byte{WebIdentityTokenFileKey: []byte("/var/run/token")},
+			expectErr: true,
+		},
+		{
+			name:      "external ID without role",
+			data:      map[string][]byte{AccessKeyIDKey: []byte("id"), SecretAccessKeyKey: []byte("secret"), ExternalIDKey: []byte("external")},
+			expectErr: true,
+		},
+		{
+			name:      "no credentials",
+			data:      map[string][]byte{RegionKey: []byte("eu-west-1")},
+			expectErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := CredentialsFromSecret(tt.data)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("expected error %v got %v", tt.expectErr, err)
+			}
+		})
+	}
+}
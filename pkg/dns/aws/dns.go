@@ -17,8 +17,10 @@ limitations under the License.
 package aws
 
 import (
+	"context"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/go-logr/logr"
 
@@ -38,6 +40,10 @@ const (
 	// chinaRoute53Endpoint is the Route 53 service endpoint used for AWS China regions.
 	chinaRoute53Endpoint = "https://route53.amazonaws.com.cn"
 
+	// validateTimeout is the deadline of the calls validating the service
+	// endpoints when the provider is created.
+	validateTimeout = 30 * time.Second
+
 	ProviderSpecificEvaluateTargetHealth       = "aws/evaluate-target-health"
 	ProviderSpecificWeight                     = "aws/weight"
 	ProviderSpecificRegion                     = "aws/region"
@@ -61,6 +67,9 @@ type Provider struct {
 type Config struct {
 	// Region is the AWS region ELBs are created in.
 	Region string
+	// Credentials of the account, the credentials of the environment are used
+	// when nil.
+	Credentials *Credentials
 }
 
 func NewProvider(config Config) (*Provider, error) {
@@ -69,12 +78,21 @@ func NewProvider(config Config) (*Provider, error) {
 		region = config.Region
 	}
 
-	sess, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+	sessConfig := &aws.Config{Region: aws.String(region)}
+	if config.Credentials != nil {
+		sessConfig.Credentials = config.Credentials.static()
+	}
+	sess, err := session.NewSession(sessConfig)
 	if err != nil {
-		return nil, fmt.Errorf("couldn't create AWS client session: %v", err)
+		return nil, fmt.Errorf("couldn't create AWS client session: %w", err)
 	}
 
 	r53Config := aws.NewConfig()
+	if config.Credentials != nil {
+		if role := config.Credentials.role(sess); role != nil {
+			r53Config = r53Config.WithCredentials(role)
+		}
+	}
 
 	// If the region is in aws china, cn-north-1 or cn-northwest-1, we should:
 	// 1. hard code route53 api endpoint to https://route53.amazonaws.com.cn and region to "cn-northwest-1"
@@ -101,8 +119,10 @@ func NewProvider(config Config) (*Provider, error) {
 		config:  config,
 		logger:  log.Log.WithName("aws-route53").WithValues("region", r53Config.Region),
 	}
-	if err := validateServiceEndpoints(p); err != nil {
-		return nil, fmt.Errorf("failed to validate AWS provider service endpoints: %v", err)
+	ctx, cancel := context.WithTimeout(context.Background(), validateTimeout)
+	defer cancel()
+	if err := validateServiceEndpoints(ctx, p); err != nil {
+		return nil, fmt.Errorf("failed to validate AWS provider service endpoints: %w", err)
 	}
 	//if p.healthCheckReconciler == nil {
 	//	p.healthCheckReconciler = newRoute53HealthCheckReconciler(p.route53, p.logger)
@@ -113,15 +133,21 @@ func NewProvider(config Config) (*Provider, error) {
 
 // validateServiceEndpoints validates that provider clients can communicate with
 // associated API endpoints by having each client make a list/describe/get call.
-func validateServiceEndpoints(provider *Provider) error {
+func validateServiceEndpoints(ctx context.Context, provider *Provider) error {
 	var errs []error
 	zoneInput := route53.ListHostedZonesInput{MaxItems: aws.String("1")}
-	if _, err := provider.route53.ListHostedZones(&zoneInput); err != nil {
-		errs = append(errs, fmt.Errorf("failed to list route53 hosted zones: %v", err))
+	if _, err := provider.route53.ListHostedZonesWithContext(ctx, &zoneInput); err != nil {
+		errs = append(errs, fmt.Errorf("failed to list route53 hosted zones: %w", err))
 	}
 	return kerrors.NewAggregate(errs)
 }
 
+// Check validates that the provider clients can communicate with the
+// Route53 API.
+func (p *Provider) Check(ctx context.Context) error {
+	return validateServiceEndpoints(ctx, p)
+}
+
 type action string
 
 const (
@@ -129,12 +155,12 @@ const (
 	deleteAction action = "DELETE"
 )
 
-func (p *Provider) Ensure(record *v1.DNSRecord, zone v1.DNSZone) error {
-	return p.change(record, zone, upsertAction)
+func (p *Provider) Ensure(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone) error {
+	return p.change(ctx, record, zone, upsertAction)
 }
 
-func (p *Provider) Delete(record *v1.DNSRecord, zone v1.DNSZone) error {
-	return p.change(record, zone, deleteAction)
+func (p *Provider) Delete(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone) error {
+	return p.change(ctx, record, zone, deleteAction)
 }
 
 //func (p *Provider) ReconcileHealthCheck(ctx context.Context, hc v1.HealthCheck, endpoint *v1.Endpoint) error {
@@ -147,11 +173,11 @@ func (p *Provider) Delete(record *v1.DNSRecord, zone v1.DNSZone) error {
 //}
 
 // change will perform an action on a record.
-func (p *Provider) change(record *v1.DNSRecord, zone v1.DNSZone, action action) error {
+func (p *Provider) change(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone, action action) error {
 	// Configure records.
-	err := p.updateRecord(record, zone.ID, string(action))
+	err := p.updateRecord(ctx, record, zone.ID, string(action))
 	if err != nil {
-		return fmt.Errorf("failed to update record in zone %s: %v", zone.ID, err)
+		return fmt.Errorf("failed to update record in zone %s: %w", zone.ID, err)
 	}
 	switch action {
 	case upsertAction:
@@ -162,7 +188,7 @@ func (p *Provider) change(record *v1.DNSRecord, zone v1.DNSZone, action action)
 	return nil
 }
 
-func (p *Provider) updateRecord(record *v1.DNSRecord, zoneID, action string) error {
+func (p *Provider) updateRecord(ctx context.Context, record *v1.DNSRecord, zoneID, action string) error {
 	input := route53.ChangeResourceRecordSetsInput{HostedZoneId: aws.String(zoneID)}
 
 	expectedEndpointsMap := make(map[string]struct{})
@@ -199,9 +225,9 @@ func (p *Provider) updateRecord(record *v1.DNSRecord, zoneID, action string) err
 	input.ChangeBatch = &route53.ChangeBatch{
 		Changes: changes,
 	}
-	resp, err := p.route53.ChangeResourceRecordSets(&input)
+	resp, err := p.route53.ChangeResourceRecordSetsWithContext(ctx, &input)
 	if err != nil {
-		return fmt.Errorf("couldn't update DNS record %s in zone %s: %v", record.Name, zoneID, err)
+		return fmt.Errorf("couldn't update DNS record %s in zone %s: %w", record.Name, zoneID, err)
 	}
 	p.logger.Info("Updated DNS record", "record", record, "zone", zoneID, "response", resp)
 	return nil
@@ -0,0 +1,124 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ProviderCache holds a provider per set of credentials. Secrets holding the
+// same credentials share a provider, and the provider of a secret is replaced
+// when its credentials change.
+type ProviderCache struct {
+	// New returns the provider managing zones with the credentials
+	New func(credentials map[string][]byte) (Provider, error)
+
+	lock sync.Mutex
+	// providers by the hash of their credentials
+	providers map[string]Provider
+	// secrets are the hashes of the credentials of the secrets
+	secrets map[types.NamespacedName]string
+}
+
+// Get returns the provider of the credentials held by the secret, creating
+// it when no secret held the credentials before. The provider is created
+// outside the lock, as creating it may call the DNS service, so a slow
+// account does not hold up the secrets of other accounts.
+func (c *ProviderCache) Get(secret types.NamespacedName, credentials map[string][]byte) (Provider, error) {
+	hash := hashCredentials(credentials)
+	c.lock.Lock()
+	provider, ok := c.providers[hash]
+	c.lock.Unlock()
+	if !ok {
+		created, err := c.New(credentials)
+		if err != nil {
+			return nil, err
+		}
+		provider = created
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.providers == nil {
+		c.providers = map[string]Provider{}
+		c.secrets = map[types.NamespacedName]string{}
+	}
+	// another secret holding the same credentials may have stored a
+	// provider meanwhile
+	if cached, ok := c.providers[hash]; ok {
+		provider = cached
+	} else {
+		c.providers[hash] = provider
+	}
+	previous, ok := c.secrets[secret]
+	c.secrets[secret] = hash
+	if ok && previous != hash {
+		c.release(previous)
+	}
+	return provider, nil
+}
+
+// Forget drops the provider of the secret unless other secrets hold the same
+// credentials.
+func (c *ProviderCache) Forget(secret types.NamespacedName) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	hash, ok := c.secrets[secret]
+	if !ok {
+		return
+	}
+	delete(c.secrets, secret)
+	c.release(hash)
+}
+
+// Len returns the number of cached providers.
+func (c *ProviderCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.providers)
+}
+
+// release drops the provider of the credentials once no secret holds them.
+func (c *ProviderCache) release(hash string) {
+	for _, h := range c.secrets {
+		if h == hash {
+			return
+		}
+	}
+	delete(c.providers, hash)
+}
+
+func hashCredentials(credentials map[string][]byte) string {
+	keys := make([]string, 0, len(credentials))
+	for key := range credentials {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, key := range keys {
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write(credentials[key])
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,99 @@
+package dns
+
+import (
+	"errors"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestProviderCache(t *testing.T) {
+	created := 0
+	cache := &ProviderCache{New: func(credentials map[string][]byte) (Provider, error) {
+		if len(credentials) == 0 {
+			return nil, errors.New("no credentials")
+		}
+		created++
+		return &deadlineProvider{}, nil
+	}}
+	secretA := types.NamespacedName{Namespace: "test", Name: "a"}
+	secretB := types.NamespacedName{Namespace: "test", Name: "b"}
+	account1 := map[string][]byte{"key": []byte("1")}
+	account2 := map[string][]byte{"key": []byte("2")}
+
+	expect := func(step string, expectCreated, expectCached int) {
+		t.Helper()
+		if created != expectCreated {
+			t.Errorf("%v: expected %v providers created got %v", step, expectCreated, created)
+		}
+		if cache.Len() != expectCached {
+			t.Errorf("%v: expected %v providers cached got %v", step, expectCached, cache.Len())
+		}
+	}
+
+	providerA, err := cache.Get(secretA, account1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expect("first secret", 1, 1)
+
+	if provider, _ := cache.Get(secretA, account1); provider != providerA {
+		t.Errorf("expected the provider of the unchanged secret to be reused")
+	}
+	expect("unchanged secret", 1, 1)
+
+	if provider, _ := cache.Get(secretB, map[string][]byte{"key": []byte("1")}); provider != providerA {
+		t.Errorf("expected secrets with the same credentials to share a provider")
+	}
+	expect("secret with the same credentials", 1, 1)
+
+	if provider, _ := cache.Get(secretA, account2); provider == providerA {
+		t.Errorf("expected a new provider for changed credentials")
+	}
+	expect("changed secret", 2, 2)
+
+	cache.Forget(secretB)
+	expect("forgotten secret", 2, 1)
+
+	if _, err := cache.Get(secretB, nil); err == nil {
+		t.Errorf("expected an error for invalid credentials")
+	}
+	expect("invalid credentials", 2, 1)
+}
+
+func TestProviderCacheSlowProvider(t *testing.T) {
+	blocked := make(chan struct{})
+	release := make(chan struct{})
+	cache := &ProviderCache{New: func(credentials map[string][]byte) (Provider, error) {
+		if string(credentials["key"]) == "slow" {
+			close(blocked)
+			<-release
+		}
+		return &deadlineProvider{}, nil
+	}}
+	secretA := types.NamespacedName{Namespace: "test", Name: "a"}
+	secretB := types.NamespacedName{Namespace: "test", Name: "b"}
+
+	done := make(chan error)
+	go func() {
+		_, err := cache.Get(secretA, map[string][]byte{"key": []byte("slow")})
+		done <- err
+	}()
+	<-blocked
+
+	// the provider of another account is created while the slow one is
+	if _, err := cache.Get(secretB, map[string][]byte{"key": []byte("fast")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cache.Len() != 1 {
+		t.Errorf("expected 1 provider cached got %v", cache.Len())
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cache.Len() != 2 {
+		t.Errorf("expected 2 providers cached got %v", cache.Len())
+	}
+}
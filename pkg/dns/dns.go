@@ -17,21 +17,119 @@ limitations under the License.
 package dns
 
 import (
+	"context"
+	"time"
+
 	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
 )
 
 // Provider knows how to manage DNS zones only as pertains to routing.
 type Provider interface {
 	// Ensure will create or update record.
-	Ensure(record *v1.DNSRecord, zone v1.DNSZone) error
+	Ensure(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone) error
 
 	// Delete will delete record.
+	Delete(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone) error
+}
+
+// Checker is implemented by the providers that can check they reach the
+// service managing the zones.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// Check returns an error when the provider can't reach the service managing
+// the zones. Providers that can't be checked are assumed reachable.
+func Check(ctx context.Context, provider Provider) error {
+	if checker, ok := provider.(Checker); ok {
+		return checker.Check(ctx)
+	}
+	return nil
+}
+
+// DefaultTimeout is the deadline of a call to a provider when none is
+// configured.
+const DefaultTimeout = 30 * time.Second
+
+// WithTimeout returns a provider setting a deadline on each call to the
+// provider. The deadline of the context of the call is kept when it is
+// earlier.
+func WithTimeout(provider Provider, timeout time.Duration) Provider {
+	if timeout <= 0 {
+		return provider
+	}
+	return &timeoutProvider{provider: provider, timeout: timeout}
+}
+
+type timeoutProvider struct {
+	provider Provider
+	timeout  time.Duration
+}
+
+func (p *timeoutProvider) Ensure(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	return p.provider.Ensure(ctx, record, zone)
+}
+
+func (p *timeoutProvider) Delete(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	return p.provider.Delete(ctx, record, zone)
+}
+
+func (p *timeoutProvider) Check(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	return Check(ctx, p.provider)
+}
+
+// LegacyProvider is a provider that doesn't take a context.
+type LegacyProvider interface {
+	Ensure(record *v1.DNSRecord, zone v1.DNSZone) error
 	Delete(record *v1.DNSRecord, zone v1.DNSZone) error
 }
 
+// NewLegacyProviderAdapter adapts a legacy provider to the Provider interface.
+// A call returns the error of the context once it is done, the call to the
+// legacy provider can't be cancelled and completes in the background.
+func NewLegacyProviderAdapter(provider LegacyProvider) Provider {
+	return &legacyProviderAdapter{provider: provider}
+}
+
+type legacyProviderAdapter struct {
+	provider LegacyProvider
+}
+
+func (a *legacyProviderAdapter) Ensure(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone) error {
+	return a.call(ctx, func() error { return a.provider.Ensure(record, zone) })
+}
+
+func (a *legacyProviderAdapter) Delete(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone) error {
+	return a.call(ctx, func() error { return a.provider.Delete(record, zone) })
+}
+
+func (a *legacyProviderAdapter) call(ctx context.Context, f func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- f() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 var _ Provider = &FakeProvider{}
 
 type FakeProvider struct{}
 
-func (_ *FakeProvider) Ensure(record *v1.DNSRecord, zone v1.DNSZone) error { return nil }
-func (_ *FakeProvider) Delete(record *v1.DNSRecord, zone v1.DNSZone) error { return nil }
+func (_ *FakeProvider) Ensure(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone) error {
+	return nil
+}
+func (_ *FakeProvider) Delete(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone) error {
+	return nil
+}
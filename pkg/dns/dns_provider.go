@@ -38,9 +38,28 @@ func newAWSDNSProvider() (Provider, error) {
 	var dnsProvider Provider
 	provider, err := dnsAWS.NewProvider(dnsAWS.Config{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create AWS DNS manager: %v", err)
+		return nil, fmt.Errorf("failed to create AWS DNS manager: %w", err)
 	}
 	dnsProvider = provider
 
 	return dnsProvider, nil
 }
+
+// DNSProviderForCredentials returns a provider managing zones with the
+// credentials read from the data of a credentials secret.
+func DNSProviderForCredentials(dnsProviderName string, data map[string][]byte) (Provider, error) {
+	switch dnsProviderName {
+	case "aws":
+		credentials, region, err := dnsAWS.CredentialsFromSecret(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AWS credentials: %w", err)
+		}
+		provider, err := dnsAWS.NewProvider(dnsAWS.Config{Region: region, Credentials: credentials})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create AWS DNS manager: %w", err)
+		}
+		return provider, nil
+	default:
+		return &FakeProvider{}, nil
+	}
+}
@@ -0,0 +1,171 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+type deadlineProvider struct {
+	deadline time.Time
+	ok       bool
+}
+
+func (p *deadlineProvider) Ensure(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone) error {
+	p.deadline, p.ok = ctx.Deadline()
+	return nil
+}
+
+func (p *deadlineProvider) Delete(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone) error {
+	p.deadline, p.ok = ctx.Deadline()
+	return nil
+}
+
+func TestWithTimeout(t *testing.T) {
+	tests := []struct {
+		name           string
+		timeout        time.Duration
+		parentTimeout  time.Duration
+		expectDeadline bool
+		expectBefore   time.Duration
+	}{
+		{
+			name:    "no timeout",
+			timeout: 0,
+		},
+		{
+			name:           "timeout",
+			timeout:        time.Minute,
+			expectDeadline: true,
+			expectBefore:   time.Minute,
+		},
+		{
+			name:           "earlier deadline of the caller is kept",
+			timeout:        time.Minute,
+			parentTimeout:  time.Second,
+			expectDeadline: true,
+			expectBefore:   time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.parentTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.parentTimeout)
+				defer cancel()
+			}
+			provider := &deadlineProvider{}
+			if err := WithTimeout(provider, tt.timeout).Ensure(ctx, &v1.DNSRecord{}, v1.DNSZone{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			end := time.Now()
+			if provider.ok != tt.expectDeadline {
+				t.Fatalf("expected deadline %v got %v", tt.expectDeadline, provider.ok)
+			}
+			if tt.expectDeadline && provider.deadline.After(end.Add(tt.expectBefore)) {
+				t.Errorf("expected deadline before %v got %v", end.Add(tt.expectBefore), provider.deadline)
+			}
+		})
+	}
+}
+
+type legacyProvider struct {
+	block chan struct{}
+	err   error
+}
+
+func (p *legacyProvider) Ensure(record *v1.DNSRecord, zone v1.DNSZone) error {
+	if p.block != nil {
+		<-p.block
+	}
+	return p.err
+}
+
+func (p *legacyProvider) Delete(record *v1.DNSRecord, zone v1.DNSZone) error {
+	return p.Ensure(record, zone)
+}
+
+func TestLegacyProviderAdapter(t *testing.T) {
+	providerErr := errors.New("provider failed")
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelExpired()
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		provider  *legacyProvider
+		expectErr error
+	}{
+		{
+			name:     "success",
+			ctx:      context.Background(),
+			provider: &legacyProvider{},
+		},
+		{
+			name:      "error of the provider",
+			ctx:       context.Background(),
+			provider:  &legacyProvider{err: providerErr},
+			expectErr: providerErr,
+		},
+		{
+			name:      "cancelled before the call",
+			ctx:       cancelled,
+			provider:  &legacyProvider{},
+			expectErr: context.Canceled,
+		},
+		{
+			name:      "deadline exceeded during the call",
+			ctx:       expired,
+			provider:  &legacyProvider{block: make(chan struct{})},
+			expectErr: context.DeadlineExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.provider.block != nil {
+				defer close(tt.provider.block)
+			}
+			provider := NewLegacyProviderAdapter(tt.provider)
+			if err := provider.Ensure(tt.ctx, &v1.DNSRecord{}, v1.DNSZone{}); !errors.Is(err, tt.expectErr) {
+				t.Errorf("expected Ensure error '%v' got '%v'", tt.expectErr, err)
+			}
+			if tt.provider.block != nil {
+				return
+			}
+			if err := provider.Delete(tt.ctx, &v1.DNSRecord{}, v1.DNSZone{}); !errors.Is(err, tt.expectErr) {
+				t.Errorf("expected Delete error '%v' got '%v'", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+type checkedProvider struct {
+	deadlineProvider
+	err error
+}
+
+func (p *checkedProvider) Check(ctx context.Context) error {
+	p.deadline, p.ok = ctx.Deadline()
+	return p.err
+}
+
+func TestCheck(t *testing.T) {
+	checkErr := errors.New("unreachable")
+	if err := Check(context.Background(), &deadlineProvider{}); err != nil {
+		t.Errorf("expected a provider without a check to be reachable got '%v'", err)
+	}
+
+	provider := &checkedProvider{err: checkErr}
+	if err := Check(context.Background(), WithTimeout(provider, time.Minute)); !errors.Is(err, checkErr) {
+		t.Errorf("expected error '%v' got '%v'", checkErr, err)
+	}
+	if !provider.ok {
+		t.Errorf("expected the check to have the deadline of the provider")
+	}
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"context"
+	"net"
+)
+
+// TXTResolver looks up the TXT records for a name.
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+var _ TXTResolver = net.DefaultResolver
+
+// DefaultTXTResolver resolves TXT records through the system resolver.
+var DefaultTXTResolver TXTResolver = net.DefaultResolver
+
+var _ TXTResolver = FakeTXTResolver{}
+
+// FakeTXTResolver resolves TXT records from a map of name to records, names
+// that are not in the map are not found.
+type FakeTXTResolver map[string][]string
+
+func (f FakeTXTResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	records, ok := f[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
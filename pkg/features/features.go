@@ -0,0 +1,181 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Feature is the name of a subsystem that can be enabled or disabled
+type Feature string
+
+// Stage is the maturity of a feature
+type Stage string
+
+const (
+	Alpha Stage = "Alpha"
+	Beta  Stage = "Beta"
+	GA    Stage = "GA"
+)
+
+const (
+	// MultiClusterWatch registers the cluster sources and watches the
+	// traffic of the workload clusters
+	MultiClusterWatch Feature = "MultiClusterWatch"
+	// SecretSync copies the TLS secrets of the published hosts to the
+	// workload clusters
+	SecretSync Feature = "SecretSync"
+	// DNSHealthCheck health checks the endpoints of the published hosts when
+	// a health check interval is configured
+	DNSHealthCheck Feature = "DNSHealthCheck"
+)
+
+// Spec is the default state of a feature and the features it depends on
+type Spec struct {
+	Default bool
+	Stage   Stage
+	// Requires are the features the feature is disabled without
+	Requires []Feature
+}
+
+var specs = map[Feature]Spec{
+	MultiClusterWatch: {Default: true, Stage: Beta},
+	SecretSync:        {Default: true, Stage: Beta, Requires: []Feature{MultiClusterWatch}},
+	DNSHealthCheck:    {Default: true, Stage: Beta},
+}
+
+// enabledGauge is a prometheus metric which holds whether each feature is
+// enabled.
+var enabledGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "mctc_feature_enabled",
+		Help: "MCTC whether a feature gate is enabled",
+	},
+	[]string{"name", "stage"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(enabledGauge)
+}
+
+// Known returns whether the name is a known feature
+func Known(name string) bool {
+	_, ok := specs[Feature(name)]
+	return ok
+}
+
+// All returns the known features sorted by name
+func All() []Feature {
+	features := make([]Feature, 0, len(specs))
+	for feature := range specs {
+		features = append(features, feature)
+	}
+	sort.Slice(features, func(i, j int) bool { return features[i] < features[j] })
+	return features
+}
+
+// Parse parses a comma separated list of name=bool pairs, the value of the
+// --feature-gates flag.
+func Parse(value string) (map[string]bool, error) {
+	gates := map[string]bool{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, enabled, found := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !found {
+			return nil, fmt.Errorf("missing value of feature gate %s, expected %s=true or %s=false", name, name, name)
+		}
+		if !Known(name) {
+			return nil, fmt.Errorf("unknown feature gate %s", name)
+		}
+		b, err := strconv.ParseBool(strings.TrimSpace(enabled))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of feature gate %s: %w", name, err)
+		}
+		gates[name] = b
+	}
+	return gates, nil
+}
+
+// Gates is the state of the features
+type Gates struct {
+	set map[Feature]bool
+}
+
+// New returns the gates of the features, the features that aren't set have
+// their default state.
+func New(set map[string]bool) (*Gates, error) {
+	g := &Gates{set: map[Feature]bool{}}
+	for name, enabled := range set {
+		if !Known(name) {
+			return nil, fmt.Errorf("unknown feature gate %s", name)
+		}
+		g.set[Feature(name)] = enabled
+	}
+	return g, nil
+}
+
+// Enabled returns whether the feature and the features it requires are
+// enabled.
+func (g *Gates) Enabled(feature Feature) bool {
+	spec, ok := specs[feature]
+	if !ok {
+		return false
+	}
+	enabled, set := g.set[feature]
+	if !set {
+		enabled = spec.Default
+	}
+	for _, required := range spec.Requires {
+		enabled = enabled && g.Enabled(required)
+	}
+	return enabled
+}
+
+// String returns the state of the features as the value of the
+// --feature-gates flag.
+func (g *Gates) String() string {
+	pairs := []string{}
+	for _, feature := range All() {
+		pairs = append(pairs, fmt.Sprintf("%s=%t", feature, g.Enabled(feature)))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Report logs the state of the features and exposes it in the
+// mctc_feature_enabled metric.
+func (g *Gates) Report(log logr.Logger) {
+	for _, feature := range All() {
+		enabled := g.Enabled(feature)
+		log.Info("feature gate", "feature", feature, "stage", specs[feature].Stage, "enabled", enabled)
+		value := 0.0
+		if enabled {
+			value = 1
+		}
+		enabledGauge.WithLabelValues(string(feature), string(specs[feature].Stage)).Set(value)
+	}
+}
@@ -0,0 +1,123 @@
+package features
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		expect    map[string]bool
+		expectErr string
+	}{
+		{
+			name:   "empty",
+			value:  "",
+			expect: map[string]bool{},
+		},
+		{
+			name:   "gates",
+			value:  "MultiClusterWatch=false, DNSHealthCheck=true",
+			expect: map[string]bool{"MultiClusterWatch": false, "DNSHealthCheck": true},
+		},
+		{
+			name:      "unknown gate",
+			value:     "Unknown=true",
+			expectErr: "unknown feature gate",
+		},
+		{
+			name:      "missing value",
+			value:     "MultiClusterWatch",
+			expectErr: "missing value",
+		},
+		{
+			name:      "invalid value",
+			value:     "MultiClusterWatch=maybe",
+			expectErr: "invalid value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gates, err := Parse(tt.value)
+			if tt.expectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectErr) {
+					t.Fatalf("expected error '%v' got '%v'", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(gates) != len(tt.expect) {
+				t.Fatalf("expected %v got %v", tt.expect, gates)
+			}
+			for name, enabled := range tt.expect {
+				if gates[name] != enabled {
+					t.Errorf("expected %v=%v got %v", name, enabled, gates[name])
+				}
+			}
+		})
+	}
+}
+
+func TestGatesEnabled(t *testing.T) {
+	tests := []struct {
+		name   string
+		set    map[string]bool
+		expect map[Feature]bool
+	}{
+		{
+			name:   "defaults",
+			expect: map[Feature]bool{MultiClusterWatch: true, SecretSync: true, DNSHealthCheck: true},
+		},
+		{
+			name:   "disabled requirement",
+			set:    map[string]bool{"MultiClusterWatch": false, "SecretSync": true},
+			expect: map[Feature]bool{MultiClusterWatch: false, SecretSync: false, DNSHealthCheck: true},
+		},
+		{
+			name:   "unknown feature",
+			expect: map[Feature]bool{"Unknown": false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gates, err := New(tt.set)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for feature, enabled := range tt.expect {
+				if actual := gates.Enabled(feature); actual != enabled {
+					t.Errorf("expected %v enabled %v got %v", feature, enabled, actual)
+				}
+			}
+		})
+	}
+
+	if _, err := New(map[string]bool{"Unknown": true}); err == nil {
+		t.Errorf("expected an error for an unknown feature gate")
+	}
+}
+
+func TestGatesReport(t *testing.T) {
+	gates, err := New(map[string]bool{"MultiClusterWatch": false})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	gates.Report(ctrl.Log)
+
+	for feature, expected := range map[Feature]float64{MultiClusterWatch: 0, SecretSync: 0, DNSHealthCheck: 1} {
+		actual := testutil.ToFloat64(enabledGauge.WithLabelValues(string(feature), string(Beta)))
+		if actual != expected {
+			t.Errorf("expected %v gauge %v got %v", feature, expected, actual)
+		}
+	}
+	if expected := "DNSHealthCheck=true,MultiClusterWatch=false,SecretSync=false"; gates.String() != expected {
+		t.Errorf("expected '%v' got '%v'", expected, gates.String())
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// Background runs a check every interval in the background, and reports the
+// result of the last run to the probes. Checks calling remote APIs run in the
+// background so the probes neither wait on them nor cancel them at their own
+// timeout, and frequent probes don't exhaust the rate limits of the APIs.
+type Background struct {
+	// Check is given the interval to complete
+	Check    func(ctx context.Context) error
+	Interval time.Duration
+
+	lock    sync.RWMutex
+	checked bool
+	err     error
+}
+
+var _ manager.Runnable = &Background{}
+var _ manager.LeaderElectionRunnable = &Background{}
+
+// Start runs the check until the context is done.
+func (b *Background) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, b.run, b.Interval)
+	return nil
+}
+
+// NeedLeaderElection is false so replicas that aren't the leader report
+// their readiness too.
+func (b *Background) NeedLeaderElection() bool {
+	return false
+}
+
+func (b *Background) run(ctx context.Context) {
+	checkCtx, cancel := context.WithTimeout(ctx, b.Interval)
+	defer cancel()
+	err := b.Check(checkCtx)
+	if ctx.Err() != nil {
+		// the check was stopped rather than failed
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.checked = true
+	b.err = err
+}
+
+// Checker returns the result of the last run, failing until the first run
+// completes.
+func (b *Background) Checker(_ *http.Request) error {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	if !b.checked {
+		return errors.New("the check has not completed yet")
+	}
+	return b.err
+}
+
+// CacheSynced returns a checker failing until the informers of the cache
+// have synced. The check waits for the sync up to the timeout.
+func CacheSynced(c cache.Cache, timeout time.Duration) healthz.Checker {
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), timeout)
+		defer cancel()
+		if !c.WaitForCacheSync(ctx) {
+			return errors.New("the informers of the control cluster have not synced")
+		}
+		return nil
+	}
+}
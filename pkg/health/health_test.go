@@ -0,0 +1,80 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBackground(t *testing.T) {
+	var lock sync.Mutex
+	calls := 0
+	checkErr := errors.New("unreachable")
+	ran := make(chan struct{}, 10)
+	check := &Background{
+		Check: func(ctx context.Context) error {
+			lock.Lock()
+			defer lock.Unlock()
+			calls++
+			ran <- struct{}{}
+			if _, ok := ctx.Deadline(); !ok {
+				t.Errorf("expected the check to have a deadline")
+			}
+			return checkErr
+		},
+		Interval: 50 * time.Millisecond,
+	}
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+
+	if err := check.Checker(req); err == nil {
+		t.Errorf("expected an error before the first check completes")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = check.Start(ctx)
+		close(done)
+	}()
+	<-ran
+	// the probes read the stored result without running the check
+	for i := 0; i < 3; i++ {
+		if err := check.Checker(req); err != checkErr {
+			t.Errorf("expected error '%v' got '%v'", checkErr, err)
+		}
+	}
+	lock.Lock()
+	if calls != 1 {
+		t.Errorf("expected 1 call within the interval got %v", calls)
+	}
+	checkErr = nil
+	lock.Unlock()
+
+	<-ran
+	if err := check.Checker(req); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	cancel()
+	<-done
+}
+
+func TestBackgroundStopped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	check := &Background{
+		Check: func(ctx context.Context) error {
+			// the check is cancelled along with the manager
+			cancel()
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		Interval: time.Minute,
+	}
+	_ = check.Start(ctx)
+	if err := check.Checker(httptest.NewRequest(http.MethodGet, "/readyz", nil)); err == nil || errors.Is(err, context.Canceled) {
+		t.Errorf("expected the cancelled check not to be stored, got: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package multiClusterWatch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
+)
+
+const (
+	// ClusterNameAnnotation is the friendly name of a workload cluster
+	ClusterNameAnnotation = "kuadrant.io/cluster-name"
+	// RegionLabel, ContinentLabel and CountryLabel locate a workload cluster,
+	// the continent is one of the geo codes of traffic policies and the
+	// country an ISO 3166-1 alpha-2 code
+	RegionLabel    = "topology.kubernetes.io/region"
+	ContinentLabel = "kuadrant.io/continent"
+	CountryLabel   = "kuadrant.io/country"
+	// WeightLabel is the weight of the endpoints of a workload cluster when
+	// no traffic policy sets one, between 0 and 255
+	WeightLabel = "kuadrant.io/weight"
+)
+
+var continents = []string{"AF", "AN", "AS", "EU", "NA", "OC", "SA"}
+
+// ClusterAttributes describe a workload cluster to the handlers of its traffic
+type ClusterAttributes struct {
+	// Name is the friendly name of the cluster, empty when it has none
+	Name      string
+	Region    string
+	Continent string
+	Country   string
+	// Weight is the default weight of the endpoints of the cluster, empty
+	// for the default weight of the handler
+	Weight string
+	// Labels are matched by the cluster selectors of traffic policies. They
+	// hold the labels of the object the cluster was registered with, and the
+	// attributes above under their label keys.
+	Labels map[string]string
+	// Scope overrides the scope of the watch controller for the cluster
+	Scope Scope
+}
+
+// ClusterAttributesFor reads the attributes of a workload cluster from the
+// labels of the object the cluster is registered with, falling back to its
+// annotations for attributes that aren't labelled.
+func ClusterAttributesFor(obj metav1.Object) (ClusterAttributes, error) {
+	get := func(key string) string {
+		if value, ok := obj.GetLabels()[key]; ok {
+			return strings.TrimSpace(value)
+		}
+		return strings.TrimSpace(obj.GetAnnotations()[key])
+	}
+
+	attributes := ClusterAttributes{
+		Name:      get(ClusterNameAnnotation),
+		Region:    get(RegionLabel),
+		Continent: strings.ToUpper(get(ContinentLabel)),
+		Country:   strings.ToUpper(get(CountryLabel)),
+		Weight:    get(WeightLabel),
+		Labels:    map[string]string{},
+	}
+	if attributes.Continent != "" && !slice.ContainsString(continents, attributes.Continent) {
+		return ClusterAttributes{}, fmt.Errorf("invalid continent '%v', expected one of %v", attributes.Continent, continents)
+	}
+	if attributes.Country != "" && len(attributes.Country) != 2 {
+		return ClusterAttributes{}, fmt.Errorf("invalid country '%v', expected an ISO 3166-1 alpha-2 code", attributes.Country)
+	}
+	if attributes.Weight != "" {
+		weight, err := strconv.Atoi(attributes.Weight)
+		if err != nil || weight < 0 || weight > 255 {
+			return ClusterAttributes{}, fmt.Errorf("invalid weight '%v', expected an integer between 0 and 255", attributes.Weight)
+		}
+	}
+
+	scope, err := ScopeFor(obj)
+	if err != nil {
+		return ClusterAttributes{}, fmt.Errorf("invalid watch scope: %w", err)
+	}
+	attributes.Scope = scope
+
+	for key, value := range obj.GetLabels() {
+		attributes.Labels[key] = value
+	}
+	for key, value := range map[string]string{
+		RegionLabel:    attributes.Region,
+		ContinentLabel: attributes.Continent,
+		CountryLabel:   attributes.Country,
+		WeightLabel:    attributes.Weight,
+	} {
+		if value != "" {
+			attributes.Labels[key] = value
+		}
+	}
+	return attributes, nil
+}
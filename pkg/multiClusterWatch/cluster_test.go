@@ -0,0 +1,79 @@
+package multiClusterWatch
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestClusterAttributesFor(t *testing.T) {
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		expected    ClusterAttributes
+		expectErr   bool
+	}{
+		{
+			name:     "no attributes",
+			labels:   map[string]string{"argocd.argoproj.io/secret-type": "cluster"},
+			expected: ClusterAttributes{Labels: map[string]string{"argocd.argoproj.io/secret-type": "cluster"}},
+		},
+		{
+			name: "attributes from labels and annotations",
+			labels: map[string]string{
+				RegionLabel:    "eu-west-1",
+				ContinentLabel: "eu",
+			},
+			annotations: map[string]string{
+				ClusterNameAnnotation: "cluster-a",
+				CountryLabel:          "ie",
+				WeightLabel:           "50",
+				ContinentLabel:        "NA",
+			},
+			expected: ClusterAttributes{
+				Name:      "cluster-a",
+				Region:    "eu-west-1",
+				Continent: "EU",
+				Country:   "IE",
+				Weight:    "50",
+				Labels: map[string]string{
+					RegionLabel:    "eu-west-1",
+					ContinentLabel: "EU",
+					CountryLabel:   "IE",
+					WeightLabel:    "50",
+				},
+			},
+		},
+		{
+			name:      "invalid continent",
+			labels:    map[string]string{ContinentLabel: "europe"},
+			expectErr: true,
+		},
+		{
+			name:      "invalid country",
+			labels:    map[string]string{CountryLabel: "irl"},
+			expectErr: true,
+		},
+		{
+			name:        "invalid weight",
+			annotations: map[string]string{WeightLabel: "256"},
+			expectErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Labels: tt.labels, Annotations: tt.annotations}}
+			attributes, err := ClusterAttributesFor(secret)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("expected error %v got %v", tt.expectErr, err)
+			}
+			if !tt.expectErr && !reflect.DeepEqual(attributes, tt.expected) {
+				t.Errorf("expected attributes %+v got %+v", tt.expected, attributes)
+			}
+		})
+	}
+}
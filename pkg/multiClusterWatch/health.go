@@ -0,0 +1,66 @@
+package multiClusterWatch
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+// CheckSynced returns a readiness check failing while the fraction of the
+// watched workload clusters whose informers have synced is below the minimum.
+// The check passes while no cluster is watched.
+func (w *WatchController) CheckSynced(minFraction float64) healthz.Checker {
+	return func(req *http.Request) error {
+		var unsynced []string
+		watchers := w.clusterWatchers()
+		for _, watcher := range watchers {
+			if !watcher.Synced() {
+				unsynced = append(unsynced, watcher.ClusterName)
+			}
+		}
+		if len(watchers) == 0 {
+			return nil
+		}
+		synced := len(watchers) - len(unsynced)
+		if float64(synced)/float64(len(watchers)) < minFraction {
+			sort.Strings(unsynced)
+			return fmt.Errorf("%d of %d workload clusters synced, below the minimum of %v, unsynced: %s",
+				synced, len(watchers), minFraction, strings.Join(unsynced, ", "))
+		}
+		return nil
+	}
+}
+
+// CheckWatchers returns a liveness check failing when a cluster watcher has
+// been handling an object for longer than the timeout. The events of a kind
+// are handled one at a time, a handler that doesn't return stops the watch
+// of its kind on the cluster.
+func (w *WatchController) CheckWatchers(timeout time.Duration) healthz.Checker {
+	return func(req *http.Request) error {
+		var stuck []string
+		for _, watcher := range w.clusterWatchers() {
+			if since, ok := watcher.handlingSince(); ok && time.Since(since) > timeout {
+				stuck = append(stuck, fmt.Sprintf("%s since %v", watcher.ClusterName, since.Format(time.RFC3339)))
+			}
+		}
+		if len(stuck) > 0 {
+			sort.Strings(stuck)
+			return fmt.Errorf("cluster watchers handling an object for longer than %v: %s", timeout, strings.Join(stuck, ", "))
+		}
+		return nil
+	}
+}
+
+func (w *WatchController) clusterWatchers() []*ClusterWatcher {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	watchers := make([]*ClusterWatcher, 0, len(w.watchers))
+	for _, watcher := range w.watchers {
+		watchers = append(watchers, watcher)
+	}
+	return watchers
+}
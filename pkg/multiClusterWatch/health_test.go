@@ -0,0 +1,78 @@
+package multiClusterWatch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWatchControllerCheckSynced(t *testing.T) {
+	tests := []struct {
+		name        string
+		synced      []bool
+		minFraction float64
+		expectErr   bool
+	}{
+		{
+			name:        "no clusters",
+			minFraction: 1,
+		},
+		{
+			name:        "every cluster synced",
+			synced:      []bool{true, true},
+			minFraction: 1,
+		},
+		{
+			name:        "enough clusters synced",
+			synced:      []bool{true, true, false},
+			minFraction: 0.5,
+		},
+		{
+			name:        "too few clusters synced",
+			synced:      []bool{true, false, false},
+			minFraction: 0.5,
+			expectErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &WatchController{watchers: map[string]*ClusterWatcher{}}
+			for i, synced := range tt.synced {
+				name := string(rune('a' + i))
+				w.watchers[name] = &ClusterWatcher{ClusterName: name, synced: synced}
+			}
+			err := w.CheckSynced(tt.minFraction)(httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if (err != nil) != tt.expectErr {
+				t.Errorf("expected error %v got '%v'", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestWatchControllerCheckWatchers(t *testing.T) {
+	watcher := &ClusterWatcher{ClusterName: "stuck-cluster"}
+	w := &WatchController{watchers: map[string]*ClusterWatcher{"stuck-cluster": watcher}}
+	check := w.CheckWatchers(time.Minute)
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+
+	if err := check(req); err != nil {
+		t.Errorf("unexpected error while idle: %v", err)
+	}
+
+	done := watcher.startHandling()
+	if err := check(req); err != nil {
+		t.Errorf("unexpected error while handling: %v", err)
+	}
+
+	// the handling started longer ago than the timeout
+	watcher.handling[watcher.nextHandle] = time.Now().Add(-2 * time.Minute)
+	if err := check(req); err == nil {
+		t.Errorf("expected an error for the stuck watcher")
+	}
+
+	done()
+	if err := check(req); err != nil {
+		t.Errorf("unexpected error once handled: %v", err)
+	}
+}
@@ -2,51 +2,100 @@ package multiClusterWatch
 
 import (
 	"context"
+	"math"
+	"sync"
 	"time"
 
-	networkingv1 "k8s.io/api/networking/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/client-go/informers"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	trafficController "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/traffic"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/tracing"
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
 )
 
 const (
 	RESYNC_PERIOD = 30 * time.Minute
+	// ERROR_REQUEUE_PERIOD is how long to wait before handling an object
+	// again after the handler failed
+	ERROR_REQUEUE_PERIOD = 30 * time.Second
+
+	// The reasons of the events recorded on the traffic objects on the
+	// workload clusters
+	HandleFailedReason    = "HandleFailed"
+	WriteBackFailedReason = "WriteBackFailed"
+
+	eventSourceComponent = "multi-cluster-traffic-controller"
 )
 
-type ResourceHandlerFactory func(c *rest.Config, controlClient client.Client) (ResourceHandler, error)
+// watchRetryBackoff is the delay before watching a workload cluster again
+// after the watch failed, growing up to five minutes on consecutive failures
+var watchRetryBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    math.MaxInt32,
+	Cap:      5 * time.Minute,
+}
+
+// ResourceHandlerFactory returns the handler of the objects observed on a
+// workload cluster with the given attributes, within the given scope.
+type ResourceHandlerFactory func(c *rest.Config, attributes ClusterAttributes, scope Scope, controlClient client.Client) (ResourceHandler, error)
 
+// ResourceHandler handles the objects observed on a workload cluster. Objects
+// that were deleted from the workload cluster are handled with their deletion
+// timestamp set.
 type ResourceHandler interface {
 	Handle(context.Context, runtime.Object) (ctrl.Result, error)
 }
 
-func NewTrafficHandlerFactory() ResourceHandlerFactory {
-	return func(config *rest.Config, controlClient client.Client) (ResourceHandler, error) {
-		c, err := client.New(config, client.Options{})
+// NewTrafficHandlerFactory returns a factory for handlers that manage the
+// traffic observed on a workload cluster and publish it as DNSRecords on the
+// control cluster.
+func NewTrafficHandlerFactory(config trafficController.ReconcilerConfig) ResourceHandlerFactory {
+	return func(restConfig *rest.Config, attributes ClusterAttributes, scope Scope, controlClient client.Client) (ResourceHandler, error) {
+		c, err := client.New(restConfig, client.Options{})
 		if err != nil {
 			return nil, err
 		}
 		trafficHandler := &trafficController.Reconciler{
-			WorkloadClient: c,
-			ControlClient:  controlClient,
+			WorkloadClient:   NewScopedClient(c, scope),
+			ControlClient:    controlClient,
+			ClusterName:      restConfig.Host,
+			ClusterLabels:    attributes.Labels,
+			ClusterWeight:    attributes.Weight,
+			ReconcilerConfig: config,
 		}
 		return trafficHandler, nil
 	}
 }
 
 type Interface interface {
-	WatchCluster(config *rest.Config) (Watcher, error)
+	// WatchCluster starts watching the workload cluster, or updates the
+	// attributes of the cluster when it is already watched
+	WatchCluster(config *rest.Config, attributes ClusterAttributes) (Watcher, error)
 }
 
 type Watcher interface {
@@ -54,100 +103,680 @@ type Watcher interface {
 }
 
 type WatchController struct {
-	watchers        map[string]Watcher
+	lock            sync.RWMutex
+	watchers        map[string]*ClusterWatcher
+	clients         map[string]client.Client
 	InformerContext context.Context
 	Manager         manager.Manager
 	HandlerFactory  ResourceHandlerFactory
+	// Scope limits the objects watched on every workload cluster, unless
+	// overridden by the attributes of the cluster
+	Scope Scope
+	// ResyncPeriod is how often the informers of the workload clusters
+	// handle every object again, RESYNC_PERIOD when zero
+	ResyncPeriod time.Duration
 }
 
 type ClusterWatcher struct {
-	ClusterName string
-	client      kubernetes.Interface
-	Handler     ResourceHandler
+	ClusterName   string
+	client        kubernetes.Interface
+	dynamicClient dynamic.Interface
+	config        *rest.Config
+	controlClient client.Client
+	factory       ResourceHandlerFactory
+	globalScope   Scope
+	resyncPeriod  time.Duration
+	broadcaster   record.EventBroadcaster
+	recorder      record.EventRecorder
+
+	lock       sync.RWMutex
+	attributes ClusterAttributes
+	handler    ResourceHandler
+	// restart stops the informers of the current scope so they are started
+	// again for a new one
+	restart context.CancelFunc
+	// synced is whether the informers of the current scope have synced
+	synced bool
+
+	stopOnce sync.Once
+	// stopped is closed to stop the watcher before the manager stops
+	stopped chan struct{}
+	// handling holds the start of the objects being handled
+	handling   map[uint64]time.Time
+	nextHandle uint64
+
+	queueOnce    sync.Once
+	requeueQueue workqueue.RateLimitingInterface
+	requeueLock  sync.Mutex
+	// requeued holds the objects of the keys in the requeue queue
+	requeued map[requeueKey]requeueItem
+
+	handledLock sync.Mutex
+	// handled holds the last handled version of the objects in the scope,
+	// to withdraw those that are no longer in the scope once it changes
+	handled map[requeueKey]requeueItem
 }
 
-func (w *WatchController) WatchCluster(config *rest.Config) (Watcher, error) {
+func (w *WatchController) WatchCluster(config *rest.Config, attributes ClusterAttributes) (Watcher, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
 	if w.watchers == nil {
-		w.watchers = map[string]Watcher{}
+		w.watchers = map[string]*ClusterWatcher{}
+		w.clients = map[string]client.Client{}
 	}
 
-	if w.watchers[config.Host] != nil {
-		return w.watchers[config.Host], nil
+	previous := w.watchers[config.Host]
+	if previous != nil && !credentialsChanged(previous.config, config) {
+		if err := previous.SetAttributes(attributes); err != nil {
+			return nil, err
+		}
+		return previous, nil
 	}
 
-	watcher, err := NewClusterWatcher(w.Manager, config, w.HandlerFactory)
+	c, err := client.New(config, client.Options{})
+	if err != nil {
+		return nil, err
+	}
+	watcher, err := NewClusterWatcher(w.Manager, config, attributes, w.Scope, w.ResyncPeriod, w.HandlerFactory)
 	if err != nil {
 		return nil, err
 	}
+	if previous != nil {
+		// the clients of the watcher keep the credentials they were
+		// created with
+		log.Log.Info("restarting cluster watcher with new credentials", "cluster", config.Host)
+		previous.Stop()
+	}
 
 	w.watchers[config.Host] = watcher
+	w.clients[config.Host] = c
+	watchedClusters.Set(float64(len(w.watchers)))
 	return watcher, nil
 }
 
+// credentialsChanged returns whether the configs authenticate to the cluster
+// differently.
+func credentialsChanged(a, b *rest.Config) bool {
+	return a.BearerToken != b.BearerToken ||
+		a.BearerTokenFile != b.BearerTokenFile ||
+		a.Username != b.Username ||
+		a.Password != b.Password ||
+		!equality.Semantic.DeepEqual(a.TLSClientConfig, b.TLSClientConfig) ||
+		!equality.Semantic.DeepEqual(a.ExecProvider, b.ExecProvider) ||
+		!equality.Semantic.DeepEqual(a.AuthProvider, b.AuthProvider)
+}
+
+// WorkloadClients returns a client for every watched workload cluster keyed
+// by the cluster name, limited to the scope of the cluster.
+func (w *WatchController) WorkloadClients() map[string]client.Client {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	clients := make(map[string]client.Client, len(w.clients))
+	for cluster, c := range w.clients {
+		clients[cluster] = NewScopedClient(c, w.watchers[cluster].Scope())
+	}
+	return clients
+}
+
+// Requeue handles the traffic object of the kind again on the workload
+// cluster, or on every workload cluster when the cluster is empty.
+func (w *WatchController) Requeue(cluster, kind, namespace, name string) {
+	var trafficKind *traffic.Kind
+	for _, k := range traffic.Kinds() {
+		if k.Name == kind {
+			trafficKind = &k
+			break
+		}
+	}
+	if trafficKind == nil {
+		return
+	}
+	u := &unstructured.Unstructured{}
+	u.SetNamespace(namespace)
+	u.SetName(name)
+
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	for name, watcher := range w.watchers {
+		if cluster == "" || cluster == name {
+			watcher.requeue(*trafficKind, "update", u, 0, false)
+		}
+	}
+}
+
+// Attributes returns the attributes the cluster was registered with.
+func (w *ClusterWatcher) Attributes() ClusterAttributes {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	return w.attributes
+}
+
+// SetAttributes replaces the attributes of the cluster, and the handler of
+// its objects with one for the new attributes. Objects already handled are
+// handled with the new attributes on their next event or resync. The objects
+// are watched again when the scope of the cluster changed, objects that left
+// the scope are handled as deleted so they are no longer published.
+func (w *ClusterWatcher) SetAttributes(attributes ClusterAttributes) error {
+	previous := w.Attributes()
+	if equality.Semantic.DeepEqual(previous, attributes) {
+		return nil
+	}
+	handler, err := w.factory(w.config, attributes, w.globalScope.Override(attributes.Scope), w.controlClient)
+	if err != nil {
+		return err
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.attributes = attributes
+	w.handler = handler
+	log.Log.Info("updated cluster attributes", "cluster watcher", w.ClusterName, "name", attributes.Name)
+	if !equality.Semantic.DeepEqual(previous.Scope, attributes.Scope) && w.restart != nil {
+		w.restart()
+	}
+	return nil
+}
+
+// Scope returns the scope of the objects watched on the cluster.
+func (w *ClusterWatcher) Scope() Scope {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	return w.globalScope.Override(w.attributes.Scope)
+}
+
+func (w *ClusterWatcher) getHandler() ResourceHandler {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	return w.handler
+}
+
 func (w *ClusterWatcher) Start(ctx context.Context) error {
-	log.Log.Info("Starting cluster watcher", "name", w.ClusterName)
+	log.Log.Info("Starting cluster watcher", "name", w.ClusterName, "friendly name", w.Attributes().Name)
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	go func() {
+		select {
+		case <-w.stopChan():
+			stop()
+		case <-ctx.Done():
+		}
+	}()
+	defer w.broadcaster.Shutdown()
+	defer w.queue().ShutDown()
+	go w.processRequeued(ctx)
 
-	informerFactory := informers.NewSharedInformerFactory(w.client, RESYNC_PERIOD)
+	// the errors of a workload cluster are retried here, an error returned to
+	// the manager would stop the watch of every cluster
+	backoff := watchRetryBackoff
+	for {
+		scopeCtx, cancel := context.WithCancel(ctx)
+		w.lock.Lock()
+		w.restart = cancel
+		w.lock.Unlock()
 
-	informer := informerFactory.Networking().V1().Ingresses().Informer()
-	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: func(obj interface{}) {
-			log.Log.Info("got add event for ingress", "cluster watcher", w.ClusterName, "ingress", obj.(*networkingv1.Ingress).Namespace+"/"+obj.(*networkingv1.Ingress).Name)
-			current := obj.(*networkingv1.Ingress)
-			target := current.DeepCopy()
-			targetAccessor := traffic.NewIngress(target)
-			_, _ = w.Handler.Handle(ctx, targetAccessor)
-			//todo handle requeue and errors
-			if !equality.Semantic.DeepEqual(current, target) {
-				//write back to cluster
-				_, _ = w.client.NetworkingV1().Ingresses(target.Namespace).Update(ctx, target, metav1.UpdateOptions{})
+		err := w.watch(scopeCtx, w.Scope())
+		if err != nil {
+			w.setUnsynced()
+			delay := backoff.Step()
+			log.Log.Error(err, "failed to watch cluster, retrying", "cluster watcher", w.ClusterName, "delay", delay)
+			select {
+			case <-scopeCtx.Done():
+			case <-time.After(delay):
 			}
+		} else {
+			backoff = watchRetryBackoff
+		}
+		cancel()
+		if ctx.Err() != nil {
+			log.Log.Info("closing watch", "cluster", w.ClusterName)
+			return nil
+		}
+		if err == nil {
+			log.Log.Info("restarting watch for a new scope", "cluster watcher", w.ClusterName)
+		}
+	}
+}
+
+// Stop stops the watcher, which is not started again.
+func (w *ClusterWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stopChan())
+	})
+}
+
+func (w *ClusterWatcher) stopChan() chan struct{} {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.stopped == nil {
+		w.stopped = make(chan struct{})
+	}
+	return w.stopped
+}
+
+// setUnsynced reports the informers of every kind as not synced.
+func (w *ClusterWatcher) setUnsynced() {
+	for _, kind := range traffic.Kinds() {
+		informerSynced.WithLabelValues(w.ClusterName, kind.Name).Set(0)
+	}
+	w.setSynced(false)
+}
+
+// watch handles the traffic objects in the scope until the context is done.
+// An informer factory is started for each watched namespace.
+func (w *ClusterWatcher) watch(ctx context.Context, scope Scope) error {
+	resyncPeriod := w.resyncPeriod
+	if resyncPeriod == 0 {
+		resyncPeriod = RESYNC_PERIOD
+	}
+	var kinds []traffic.Kind
+	stores := map[string][]cache.Store{}
+	for _, kind := range traffic.Kinds() {
+		served, err := w.isServed(kind.GVR)
+		if err != nil {
+			return err
+		}
+		if !served {
+			log.Log.Info("resource is not served, skipping watch", "cluster watcher", w.ClusterName, "resource", kind.GVR.String())
+			continue
+		}
+		kinds = append(kinds, kind)
+	}
+
+	synced := map[schema.GroupVersionResource]bool{}
+	for _, kind := range kinds {
+		informerSynced.WithLabelValues(w.ClusterName, kind.Name).Set(0)
+		synced[kind.GVR] = true
+	}
+	defer func() {
+		for _, kind := range kinds {
+			informerSynced.WithLabelValues(w.ClusterName, kind.Name).Set(0)
+		}
+		w.setSynced(false)
+	}()
+
+	for _, namespace := range scope.namespaces() {
+		informerFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(w.dynamicClient, resyncPeriod, namespace, scope.tweakListOptions)
+		for _, kind := range kinds {
+			informer := informerFactory.ForResource(kind.GVR).Informer()
+			informer.AddEventHandler(w.eventHandler(ctx, kind))
+			stores[kind.Name] = append(stores[kind.Name], informer.GetStore())
+		}
+		informerFactory.Start(ctx.Done())
+		for gvr, ok := range informerFactory.WaitForCacheSync(ctx.Done()) {
+			synced[gvr] = synced[gvr] && ok
+		}
+	}
+	allSynced := ctx.Err() == nil
+	for _, kind := range kinds {
+		if synced[kind.GVR] {
+			informerSynced.WithLabelValues(w.ClusterName, kind.Name).Set(1)
+		} else {
+			allSynced = false
+		}
+	}
+	w.setSynced(allSynced)
+	if allSynced {
+		w.withdrawOutOfScope(ctx, stores)
+	}
+
+	log.Log.Info("started watcher events", "cluster watcher", w.ClusterName, "namespaces", scope.Namespaces, "excluded namespaces", scope.ExcludedNamespaces, "label selector", scope.LabelSelector)
+
+	<-ctx.Done()
+	return nil
+}
+
+// withdrawOutOfScope handles the objects handled in a previous scope that
+// are not in the synced stores of the current one as deleted, so they are no
+// longer published.
+func (w *ClusterWatcher) withdrawOutOfScope(ctx context.Context, stores map[string][]cache.Store) {
+	w.handledLock.Lock()
+	var withdrawn []requeueItem
+	for key, handled := range w.handled {
+		inScope := false
+		for _, store := range stores[key.kind] {
+			if _, exists, _ := store.GetByKey(key.namespace + "/" + key.name); exists {
+				inScope = true
+				break
+			}
+		}
+		if !inScope {
+			withdrawn = append(withdrawn, handled)
+		}
+	}
+	w.handledLock.Unlock()
+
+	for _, handled := range withdrawn {
+		log.Log.Info("withdrawing object that left the scope", "cluster watcher", w.ClusterName, "kind", handled.kind.Name, "name", handled.obj.GetNamespace()+"/"+handled.obj.GetName())
+		w.handle(ctx, handled.kind, "delete", handled.obj)
+	}
+}
+
+// setHandled records the last handled version of an object, forgetting
+// deleted objects.
+func (w *ClusterWatcher) setHandled(kind traffic.Kind, event string, u *unstructured.Unstructured) {
+	w.handledLock.Lock()
+	defer w.handledLock.Unlock()
+	if w.handled == nil {
+		w.handled = map[requeueKey]requeueItem{}
+	}
+	key := requeueKeyFor(kind, u)
+	if event == "delete" {
+		delete(w.handled, key)
+		return
+	}
+	w.handled[key] = requeueItem{kind: kind, event: event, obj: u}
+}
+
+// Synced returns whether the informers of the cluster have synced.
+func (w *ClusterWatcher) Synced() bool {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	return w.synced
+}
+
+func (w *ClusterWatcher) setSynced(synced bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.synced = synced
+}
+
+// startHandling records that an object is being handled until the returned
+// function is called.
+func (w *ClusterWatcher) startHandling() func() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.handling == nil {
+		w.handling = map[uint64]time.Time{}
+	}
+	w.nextHandle++
+	id := w.nextHandle
+	w.handling[id] = time.Now()
+	return func() {
+		w.lock.Lock()
+		defer w.lock.Unlock()
+		delete(w.handling, id)
+	}
+}
+
+// handlingSince returns the start of the longest running handling of an
+// object, false when no object is being handled.
+func (w *ClusterWatcher) handlingSince() (time.Time, bool) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	var oldest time.Time
+	for _, start := range w.handling {
+		if oldest.IsZero() || start.Before(oldest) {
+			oldest = start
+		}
+	}
+	return oldest, !oldest.IsZero()
+}
+
+// isServed returns whether the workload cluster serves the given resource.
+func (w *ClusterWatcher) isServed(gvr schema.GroupVersionResource) (bool, error) {
+	resources, err := w.client.Discovery().ServerResourcesForGroupVersion(gvr.GroupVersion().String())
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, r := range resources.APIResources {
+		if r.Name == gvr.Resource {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (w *ClusterWatcher) eventHandler(ctx context.Context, kind traffic.Kind) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			w.handle(ctx, kind, "add", obj)
 		},
 		UpdateFunc: func(old, obj interface{}) {
-			log.Log.Info("got update event for ingress", "cluster watcher", w.ClusterName, "ingress", obj.(*networkingv1.Ingress).Namespace+"/"+obj.(*networkingv1.Ingress).Name)
-			current := obj.(*networkingv1.Ingress)
-			target := current.DeepCopy()
-			targetAccessor := traffic.NewIngress(target)
-			_, _ = w.Handler.Handle(ctx, targetAccessor)
-			//todo handle requeue and errors
-			if !equality.Semantic.DeepEqual(current, target) {
-				//write back to cluster
-				_, _ = w.client.NetworkingV1().Ingresses(target.Namespace).Update(ctx, target, metav1.UpdateOptions{})
-			}
+			w.handle(ctx, kind, "update", obj)
 		},
 		DeleteFunc: func(obj interface{}) {
-			log.Log.Info("got delete event for ingress", "cluster watcher", w.ClusterName, "ingress", obj.(*networkingv1.Ingress).Namespace+"/"+obj.(*networkingv1.Ingress).Name)
-			current := obj.(*networkingv1.Ingress)
-			target := current.DeepCopy()
-			targetAccessor := traffic.NewIngress(target)
-			_, _ = w.Handler.Handle(ctx, targetAccessor)
-			//todo handle requeue and errors
-			if !equality.Semantic.DeepEqual(current, target) {
-				//write back to cluster
-				_, _ = w.client.NetworkingV1().Ingresses(target.Namespace).Update(ctx, target, metav1.UpdateOptions{})
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
 			}
+			w.handle(ctx, kind, "delete", obj)
 		},
+	}
+}
+
+func (w *ClusterWatcher) handle(ctx context.Context, kind traffic.Kind, event string, obj interface{}) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	current, err := kind.New(u)
+	if err != nil {
+		log.Log.Error(err, "failed to convert traffic object", "cluster watcher", w.ClusterName, "kind", kind.Name, "name", u.GetNamespace()+"/"+u.GetName())
+		return
+	}
+	if kind.Filter != nil && !kind.Filter(current) {
+		return
+	}
+	log.Log.Info("got "+event+" event", "cluster watcher", w.ClusterName, "kind", kind.Name, "name", current.GetCacheKey())
+	defer w.startHandling()()
+	w.setHandled(kind, event, u)
+	watchEventsTotal.WithLabelValues(w.ClusterName, kind.Name, event).Inc()
+
+	// the span ends before the object is requeued, a requeued object is
+	// handled in a trace of its own
+	spanCtx, span := tracing.Tracer().Start(ctx, "ClusterWatcher.handle", trace.WithAttributes(
+		attribute.String("cluster", w.ClusterName),
+		attribute.String("kind", kind.Name),
+		attribute.String("event", event),
+		attribute.String("name", current.GetCacheKey()),
+	))
+
+	resource := w.dynamicClient.Resource(kind.GVR).Namespace(current.GetNamespace())
+	handler := w.getHandler()
+	var result ctrl.Result
+	var handleErr error
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		target, err := kind.New(u.DeepCopy())
+		if err != nil {
+			return err
+		}
+		if event == "delete" && target.GetDeletionTimestamp() == nil {
+			now := metav1.Now()
+			target.SetDeletionTimestamp(&now)
+		}
+		start := time.Now()
+		handleCtx, handleSpan := tracing.Tracer().Start(spanCtx, "ResourceHandler.Handle")
+		result, handleErr = handler.Handle(handleCtx, target)
+		tracing.End(handleSpan, handleErr)
+		handlerDuration.WithLabelValues(w.ClusterName, kind.Name).Observe(time.Since(start).Seconds())
+		if event == "delete" {
+			return nil
+		}
+		if equality.Semantic.DeepEqual(current, target) {
+			writeBackTotal.WithLabelValues(w.ClusterName, kind.Name, writeBackUnchanged).Inc()
+			return nil
+		}
+		//write back to cluster
+		writeBackCtx, writeBackSpan := tracing.Tracer().Start(spanCtx, "traffic.Kind.WriteBack")
+		err = kind.WriteBack(writeBackCtx, resource, current, target)
+		tracing.End(writeBackSpan, err)
+		switch {
+		case err == nil:
+			writeBackTotal.WithLabelValues(w.ClusterName, kind.Name, writeBackUpdated).Inc()
+		case errors.IsConflict(err):
+			writeBackTotal.WithLabelValues(w.ClusterName, kind.Name, writeBackConflict).Inc()
+		default:
+			writeBackTotal.WithLabelValues(w.ClusterName, kind.Name, writeBackError).Inc()
+		}
+		if errors.IsConflict(err) {
+			// handle the latest version of the object on the next attempt
+			latest, getErr := resource.Get(spanCtx, current.GetName(), metav1.GetOptions{})
+			if getErr != nil {
+				return getErr
+			}
+			u = latest
+			if current, getErr = kind.New(u); getErr != nil {
+				return getErr
+			}
+		}
+		return err
 	})
+	if err != nil {
+		log.Log.Error(err, "failed to write back traffic object", "cluster watcher", w.ClusterName, "kind", kind.Name, "name", current.GetCacheKey())
+		w.recorder.Eventf(u, corev1.EventTypeWarning, WriteBackFailedReason, "Failed to write back the changes of the multi-cluster traffic controller: %v", err)
+	}
+	if handleErr != nil {
+		handlerErrors.WithLabelValues(w.ClusterName, kind.Name).Inc()
+		log.Log.Error(handleErr, "failed to handle traffic object", "cluster watcher", w.ClusterName, "kind", kind.Name, "name", current.GetCacheKey())
+		w.recorder.Eventf(u, corev1.EventTypeWarning, HandleFailedReason, "Failed to handle the %v event: %v", event, handleErr)
+	}
+	if handleErr != nil {
+		err = handleErr
+	}
+	tracing.End(span, err)
+	switch {
+	case handleErr != nil:
+		w.requeue(kind, event, u, 0, true)
+	case result.Requeue || result.RequeueAfter > 0:
+		w.requeue(kind, event, u, result.RequeueAfter, false)
+	default:
+		w.queue().Forget(requeueKeyFor(kind, u))
+	}
+}
 
-	informerFactory.Start(ctx.Done())
-	informerFactory.WaitForCacheSync(ctx.Done())
+// requeueKey identifies an object handled again by the requeue queue
+type requeueKey struct {
+	kind      string
+	namespace string
+	name      string
+}
 
-	log.Log.Info("started watcher events", "cluster watcher", w.ClusterName)
+// requeueItem is the object to handle again for a requeue key
+type requeueItem struct {
+	kind  traffic.Kind
+	event string
+	obj   *unstructured.Unstructured
+}
 
-	<-ctx.Done()
-	log.Log.Info("closing watch", "cluster", w.ClusterName)
-	return nil
+func requeueKeyFor(kind traffic.Kind, u *unstructured.Unstructured) requeueKey {
+	return requeueKey{kind: kind.Name, namespace: u.GetNamespace(), name: u.GetName()}
+}
+
+// queue returns the queue of the objects to handle again. Objects that
+// failed are retried with an exponential backoff up to the resync period,
+// an object is queued once however many times it is requeued.
+func (w *ClusterWatcher) queue() workqueue.RateLimitingInterface {
+	w.queueOnce.Do(func() {
+		maxDelay := w.resyncPeriod
+		if maxDelay == 0 {
+			maxDelay = RESYNC_PERIOD
+		}
+		w.requeueQueue = workqueue.NewRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(ERROR_REQUEUE_PERIOD, maxDelay))
+	})
+	return w.requeueQueue
+}
+
+// requeue handles the object again after the delay, or after the backoff of
+// the object when its handling failed.
+func (w *ClusterWatcher) requeue(kind traffic.Kind, event string, u *unstructured.Unstructured, delay time.Duration, failed bool) {
+	key := requeueKeyFor(kind, u)
+	w.requeueLock.Lock()
+	if w.requeued == nil {
+		w.requeued = map[requeueKey]requeueItem{}
+	}
+	w.requeued[key] = requeueItem{kind: kind, event: event, obj: u}
+	requeueDepth.WithLabelValues(w.ClusterName).Set(float64(len(w.requeued)))
+	w.requeueLock.Unlock()
+
+	if failed {
+		w.queue().AddRateLimited(key)
+	} else {
+		w.queue().AddAfter(key, delay)
+	}
+}
+
+// processRequeued handles the requeued objects until the queue is shut down.
+// Objects that still exist are handled in their latest version, deleted
+// objects are handled again as they were last seen.
+func (w *ClusterWatcher) processRequeued(ctx context.Context) {
+	queue := w.queue()
+	for {
+		item, shutdown := queue.Get()
+		if shutdown {
+			return
+		}
+		key := item.(requeueKey)
+		w.requeueLock.Lock()
+		requeued, ok := w.requeued[key]
+		delete(w.requeued, key)
+		requeueDepth.WithLabelValues(w.ClusterName).Set(float64(len(w.requeued)))
+		w.requeueLock.Unlock()
+		if ok {
+			w.handleRequeued(ctx, requeued)
+		}
+		queue.Done(item)
+	}
 }
 
-func NewClusterWatcher(mgr manager.Manager, config *rest.Config, handlerFactory ResourceHandlerFactory) (Watcher, error) {
+func (w *ClusterWatcher) handleRequeued(ctx context.Context, requeued requeueItem) {
+	kind, u := requeued.kind, requeued.obj
+	if requeued.event == "delete" {
+		w.handle(ctx, kind, requeued.event, u)
+		return
+	}
+	latest, err := w.dynamicClient.Resource(kind.GVR).Namespace(u.GetNamespace()).Get(ctx, u.GetName(), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		// the delete event handles the object
+		w.queue().Forget(requeueKeyFor(kind, u))
+		return
+	}
+	if err != nil {
+		log.Log.Error(err, "failed to get traffic object to requeue", "cluster watcher", w.ClusterName, "kind", kind.Name, "name", u.GetNamespace()+"/"+u.GetName())
+		w.requeue(kind, requeued.event, u, 0, true)
+		return
+	}
+	w.handle(ctx, kind, "requeue", latest)
+}
+
+// NewClusterWatcher adds a watcher of the cluster to the manager. The
+// informers of the watcher resync at the resync period, RESYNC_PERIOD when
+// zero.
+func NewClusterWatcher(mgr manager.Manager, config *rest.Config, attributes ClusterAttributes, scope Scope, resyncPeriod time.Duration, handlerFactory ResourceHandlerFactory) (*ClusterWatcher, error) {
 	log.Log.Info("creating new cluster watcher", "host", config.Host)
 	watcherClient, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		return nil, err
 	}
 
-	handler, _ := handlerFactory(config, mgr.GetClient())
-	watcher := &ClusterWatcher{client: watcherClient, ClusterName: config.Host, Handler: handler}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	handler, err := handlerFactory(config, attributes, scope.Override(attributes.Scope), mgr.GetClient())
+	if err != nil {
+		return nil, err
+	}
+
+	// events are recorded on the traffic objects on their own cluster
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: watcherClient.CoreV1().Events("")})
+	recorder := broadcaster.NewRecorder(clientgoscheme.Scheme, corev1.EventSource{Component: eventSourceComponent})
+	watcher := &ClusterWatcher{
+		ClusterName:   config.Host,
+		client:        watcherClient,
+		dynamicClient: dynamicClient,
+		config:        config,
+		controlClient: mgr.GetClient(),
+		factory:       handlerFactory,
+		globalScope:   scope,
+		broadcaster:   broadcaster,
+		recorder:      recorder,
+		attributes:    attributes,
+		handler:       handler,
+		resyncPeriod:  resyncPeriod,
+	}
 	err = mgr.Add(watcher)
 	if err != nil {
 		log.Log.Error(err, "error Adding cluster watcher the Manager")
@@ -0,0 +1,325 @@
+package multiClusterWatch
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+type handlerFunc func(ctx context.Context, o runtime.Object) (ctrl.Result, error)
+
+func (f handlerFunc) Handle(ctx context.Context, o runtime.Object) (ctrl.Result, error) {
+	return f(ctx, o)
+}
+
+func testIngress(t *testing.T) (*networkingv1.Ingress, *unstructured.Unstructured, traffic.Kind) {
+	t.Helper()
+	ingress := &networkingv1.Ingress{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "Ingress"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-ingress", Namespace: "test-namespace"},
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(ingress)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var kind traffic.Kind
+	for _, k := range traffic.Kinds() {
+		if k.Name == "Ingress" {
+			kind = k
+		}
+	}
+	return ingress, &unstructured.Unstructured{Object: content}, kind
+}
+
+func TestClusterWatcherHandle(t *testing.T) {
+	ingress, u, kind := testIngress(t)
+
+	var handleErr error
+	label := "handled"
+	recorder := record.NewFakeRecorder(10)
+	w := &ClusterWatcher{
+		recorder:      recorder,
+		ClusterName:   "metrics-cluster",
+		dynamicClient: dynamicfake.NewSimpleDynamicClient(clientgoscheme.Scheme, ingress),
+		handler: handlerFunc(func(ctx context.Context, o runtime.Object) (ctrl.Result, error) {
+			o.(traffic.Interface).SetLabels(map[string]string{"test": label})
+			return ctrl.Result{}, handleErr
+		}),
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	expect := func(name string, actual, expected float64) {
+		t.Helper()
+		if actual != expected {
+			t.Errorf("expected %v %v got %v", name, expected, actual)
+		}
+	}
+
+	w.handle(ctx, kind, "add", u)
+	expect("add events", testutil.ToFloat64(watchEventsTotal.WithLabelValues("metrics-cluster", "Ingress", "add")), 1)
+	expect("updated write-backs", testutil.ToFloat64(writeBackTotal.WithLabelValues("metrics-cluster", "Ingress", writeBackUpdated)), 1)
+	expect("handler durations", float64(testutil.CollectAndCount(handlerDuration, "mctc_watch_handler_duration_seconds")), 1)
+
+	u.SetLabels(map[string]string{"test": label})
+	w.handle(ctx, kind, "update", u)
+	expect("unchanged write-backs", testutil.ToFloat64(writeBackTotal.WithLabelValues("metrics-cluster", "Ingress", writeBackUnchanged)), 1)
+
+	handleErr = errors.New("handler failed")
+	w.handle(ctx, kind, "update", u)
+	expect("handler errors", testutil.ToFloat64(handlerErrors.WithLabelValues("metrics-cluster", "Ingress")), 1)
+	expect("requeue depth", testutil.ToFloat64(requeueDepth.WithLabelValues("metrics-cluster")), 1)
+	// the object is queued once however often it fails
+	w.handle(ctx, kind, "update", u)
+	expect("requeue depth", testutil.ToFloat64(requeueDepth.WithLabelValues("metrics-cluster")), 1)
+	<-recorder.Events
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.HasPrefix(event, "Warning "+HandleFailedReason) {
+			t.Errorf("expected a %v warning got '%v'", HandleFailedReason, event)
+		}
+	default:
+		t.Errorf("expected an event for the handler error")
+	}
+}
+
+func TestWatchControllerRequeue(t *testing.T) {
+	_, _, kind := testIngress(t)
+	a := &ClusterWatcher{ClusterName: "cluster-a"}
+	b := &ClusterWatcher{ClusterName: "cluster-b"}
+	w := &WatchController{watchers: map[string]*ClusterWatcher{"cluster-a": a, "cluster-b": b}}
+
+	w.Requeue("cluster-a", kind.Name, "test-namespace", "test-ingress")
+	w.Requeue("", kind.Name, "test-namespace", "other-ingress")
+	w.Requeue("", "Unknown", "test-namespace", "test-ingress")
+
+	expect := func(watcher *ClusterWatcher, names ...string) {
+		t.Helper()
+		if len(watcher.requeued) != len(names) {
+			t.Errorf("expected %v requeued on %v got: %v", names, watcher.ClusterName, watcher.requeued)
+		}
+		for _, name := range names {
+			if _, ok := watcher.requeued[requeueKey{kind: kind.Name, namespace: "test-namespace", name: name}]; !ok {
+				t.Errorf("expected %v to be requeued on %v", name, watcher.ClusterName)
+			}
+		}
+	}
+	expect(a, "test-ingress", "other-ingress")
+	expect(b, "other-ingress")
+}
+
+func TestClusterWatcherWithdrawOutOfScope(t *testing.T) {
+	_, inScope, kind := testIngress(t)
+	outOfScope := inScope.DeepCopy()
+	outOfScope.SetNamespace("other-namespace")
+	var deleted []string
+	w := &ClusterWatcher{
+		recorder:      record.NewFakeRecorder(10),
+		ClusterName:   "scope-cluster",
+		dynamicClient: dynamicfake.NewSimpleDynamicClient(clientgoscheme.Scheme),
+		handler: handlerFunc(func(ctx context.Context, o runtime.Object) (ctrl.Result, error) {
+			if t := o.(traffic.Interface); t.GetDeletionTimestamp() != nil {
+				deleted = append(deleted, t.GetCacheKey())
+			}
+			return ctrl.Result{}, nil
+		}),
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	w.handle(ctx, kind, "add", inScope)
+	w.handle(ctx, kind, "add", outOfScope)
+
+	// the scope changes to the namespace of the first object only
+	store := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	if err := store.Add(inScope); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w.withdrawOutOfScope(ctx, map[string][]cache.Store{kind.Name: {store}})
+	if len(deleted) != 1 || deleted[0] != "other-namespace/test-ingress" {
+		t.Fatalf("expected the object out of the scope to be handled as deleted, got: %v", deleted)
+	}
+
+	// a withdrawn object is only withdrawn once
+	w.withdrawOutOfScope(ctx, map[string][]cache.Store{kind.Name: {store}})
+	if len(deleted) != 1 {
+		t.Errorf("expected the object to be withdrawn once, got: %v", deleted)
+	}
+}
+
+type failingDiscovery struct {
+	*discoveryfake.FakeDiscovery
+	calls int32
+}
+
+func (d *failingDiscovery) ServerResourcesForGroupVersion(groupVersion string) (*metav1.APIResourceList, error) {
+	atomic.AddInt32(&d.calls, 1)
+	return nil, errors.New("connection refused")
+}
+
+type failingClientset struct {
+	*kubefake.Clientset
+	discovery *failingDiscovery
+}
+
+func (c *failingClientset) Discovery() discovery.DiscoveryInterface {
+	return c.discovery
+}
+
+func TestClusterWatcherStartRetries(t *testing.T) {
+	previous := watchRetryBackoff
+	watchRetryBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 2, Steps: 10, Cap: 10 * time.Millisecond}
+	defer func() { watchRetryBackoff = previous }()
+
+	clientset := kubefake.NewSimpleClientset()
+	d := &failingDiscovery{FakeDiscovery: clientset.Discovery().(*discoveryfake.FakeDiscovery)}
+	w := &ClusterWatcher{
+		ClusterName: "unreachable-cluster",
+		client:      &failingClientset{Clientset: clientset, discovery: d},
+		broadcaster: record.NewBroadcaster(),
+		recorder:    record.NewFakeRecorder(10),
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	done := make(chan error)
+	go func() { done <- w.Start(ctx) }()
+
+	if err := wait.PollImmediate(time.Millisecond, 5*time.Second, func() (bool, error) {
+		return atomic.LoadInt32(&d.calls) >= 3, nil
+	}); err != nil {
+		t.Errorf("expected the watch to be retried after discovery errors")
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("expected the watcher to keep running got '%v'", err)
+	default:
+	}
+	if w.Synced() {
+		t.Errorf("expected the watcher not to be synced")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("expected the watcher to stop")
+	}
+}
+
+func TestClusterWatcherStop(t *testing.T) {
+	clientset := kubefake.NewSimpleClientset()
+	d := &failingDiscovery{FakeDiscovery: clientset.Discovery().(*discoveryfake.FakeDiscovery)}
+	w := &ClusterWatcher{
+		ClusterName: "test-cluster",
+		client:      &failingClientset{Clientset: clientset, discovery: d},
+		broadcaster: record.NewBroadcaster(),
+		recorder:    record.NewFakeRecorder(10),
+	}
+	done := make(chan error)
+	go func() { done <- w.Start(context.TODO()) }()
+
+	// the watcher stops while the manager keeps running
+	w.Stop()
+	w.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("expected the watcher to stop")
+	}
+}
+
+func TestCredentialsChanged(t *testing.T) {
+	base := &rest.Config{
+		Host:            "https://cluster.example.com",
+		BearerToken:     "token",
+		TLSClientConfig: rest.TLSClientConfig{CAData: []byte("ca")},
+	}
+	tests := []struct {
+		name   string
+		modify func(c *rest.Config)
+		expect bool
+	}{
+		{name: "same credentials", modify: func(c *rest.Config) {}},
+		{name: "other settings", modify: func(c *rest.Config) { c.QPS = 10 }},
+		{name: "rotated token", modify: func(c *rest.Config) { c.BearerToken = "rotated" }, expect: true},
+		{name: "client certificate", modify: func(c *rest.Config) { c.CertData = []byte("cert") }, expect: true},
+		{name: "exec provider", modify: func(c *rest.Config) { c.ExecProvider = &clientcmdapi.ExecConfig{Command: "login"} }, expect: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := rest.CopyConfig(base)
+			tt.modify(config)
+			if changed := credentialsChanged(base, config); changed != tt.expect {
+				t.Errorf("expected changed %v got %v", tt.expect, changed)
+			}
+		})
+	}
+}
+
+func TestClusterWatcherHandleTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer otel.SetTracerProvider(previous)
+
+	ingress, u, kind := testIngress(t)
+	w := &ClusterWatcher{
+		recorder:      record.NewFakeRecorder(10),
+		ClusterName:   "tracing-cluster",
+		dynamicClient: dynamicfake.NewSimpleDynamicClient(clientgoscheme.Scheme, ingress),
+		handler: handlerFunc(func(ctx context.Context, o runtime.Object) (ctrl.Result, error) {
+			o.(traffic.Interface).SetLabels(map[string]string{"test": "traced"})
+			return ctrl.Result{}, nil
+		}),
+	}
+	w.handle(context.TODO(), kind, "add", u)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans got %v", len(spans))
+	}
+	root := spans[2]
+	if root.Name != "ClusterWatcher.handle" {
+		t.Fatalf("expected span 'ClusterWatcher.handle' got '%v'", root.Name)
+	}
+	for i, name := range []string{"ResourceHandler.Handle", "traffic.Kind.WriteBack"} {
+		if spans[i].Name != name {
+			t.Errorf("expected span '%v' got '%v'", name, spans[i].Name)
+		}
+		if spans[i].Parent.SpanID() != root.SpanContext.SpanID() {
+			t.Errorf("expected span '%v' to be a child of the handle span", name)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package multiClusterWatch
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	clusterLabel = "cluster"
+	kindLabel    = "kind"
+	eventLabel   = "event"
+	resultLabel  = "result"
+
+	writeBackUpdated   = "updated"
+	writeBackUnchanged = "unchanged"
+	writeBackConflict  = "conflict"
+	writeBackError     = "error"
+)
+
+var (
+	// watchedClusters is a prometheus metric which holds the number of
+	// watched workload clusters.
+	watchedClusters = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "mctc_watch_clusters",
+			Help: "MCTC number of watched workload clusters",
+		},
+	)
+
+	// informerSynced is a prometheus metric which is 1 when the informers of
+	// a kind on a workload cluster have synced, and 0 otherwise.
+	informerSynced = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mctc_watch_informer_synced",
+			Help: "MCTC whether the informers of a kind on a workload cluster have synced",
+		},
+		[]string{clusterLabel, kindLabel},
+	)
+
+	// watchEventsTotal is a prometheus counter metrics which holds the total
+	// number of events received from the workload clusters.
+	watchEventsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mctc_watch_events_total",
+			Help: "MCTC total number of events received from the workload clusters",
+		},
+		[]string{clusterLabel, kindLabel, eventLabel},
+	)
+
+	// handlerDuration is a prometheus metric which records the duration of
+	// the handling of the traffic objects.
+	handlerDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "mctc_watch_handler_duration_seconds",
+			Help:    "MCTC duration of the handling of workload cluster traffic objects",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{clusterLabel, kindLabel},
+	)
+
+	// handlerErrors is a prometheus counter metrics which holds the total
+	// number of failed handlings of traffic objects.
+	handlerErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mctc_watch_handler_errors_total",
+			Help: "MCTC total number of errors handling workload cluster traffic objects",
+		},
+		[]string{clusterLabel, kindLabel},
+	)
+
+	// writeBackTotal is a prometheus counter metrics which holds the total
+	// number of write-backs of traffic objects by result: updated, unchanged,
+	// conflict or error.
+	writeBackTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mctc_watch_write_back_total",
+			Help: "MCTC total number of write-backs of workload cluster traffic objects",
+		},
+		[]string{clusterLabel, kindLabel, resultLabel},
+	)
+
+	// requeueDepth is a prometheus metric which holds the number of traffic
+	// objects waiting to be handled again.
+	requeueDepth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mctc_watch_requeue_depth",
+			Help: "MCTC number of workload cluster traffic objects waiting to be handled again",
+		},
+		[]string{clusterLabel},
+	)
+)
+
+func init() {
+	// Register metrics into the global prometheus registry
+	metrics.Registry.MustRegister(
+		watchedClusters,
+		informerSynced,
+		watchEventsTotal,
+		handlerDuration,
+		handlerErrors,
+		writeBackTotal,
+		requeueDepth,
+	)
+}
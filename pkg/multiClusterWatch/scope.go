@@ -0,0 +1,190 @@
+package multiClusterWatch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+const (
+	// WatchNamespacesAnnotation, WatchExcludedNamespacesAnnotation and
+	// WatchLabelSelectorAnnotation scope the objects watched on a workload
+	// cluster, overriding the scope of the watch controller. The namespaces
+	// are comma separated.
+	WatchNamespacesAnnotation         = "kuadrant.io/watch-namespaces"
+	WatchExcludedNamespacesAnnotation = "kuadrant.io/watch-excluded-namespaces"
+	WatchLabelSelectorAnnotation      = "kuadrant.io/watch-label-selector"
+)
+
+// Scope limits the traffic objects watched on a workload cluster, so the
+// controller only needs access to those objects. The objects in every
+// namespace are watched when no namespaces are listed.
+type Scope struct {
+	Namespaces         []string
+	ExcludedNamespaces []string
+	LabelSelector      string
+}
+
+// ScopeFor reads the scope of a workload cluster from the annotations of the
+// object the cluster is registered with.
+func ScopeFor(obj metav1.Object) (Scope, error) {
+	scope := Scope{
+		Namespaces:         SplitNamespaces(obj.GetAnnotations()[WatchNamespacesAnnotation]),
+		ExcludedNamespaces: SplitNamespaces(obj.GetAnnotations()[WatchExcludedNamespacesAnnotation]),
+		LabelSelector:      strings.TrimSpace(obj.GetAnnotations()[WatchLabelSelectorAnnotation]),
+	}
+	return scope, scope.Validate()
+}
+
+// SplitNamespaces splits a comma separated list of namespaces.
+func SplitNamespaces(namespaces string) []string {
+	var split []string
+	for _, namespace := range strings.Split(namespaces, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			split = append(split, namespace)
+		}
+	}
+	return split
+}
+
+// Validate returns an error for an invalid label selector.
+func (s Scope) Validate() error {
+	_, err := labels.Parse(s.LabelSelector)
+	return err
+}
+
+// Override returns the scope with the fields set in the override replacing
+// its own.
+func (s Scope) Override(override Scope) Scope {
+	if len(override.Namespaces) > 0 {
+		s.Namespaces = override.Namespaces
+	}
+	if len(override.ExcludedNamespaces) > 0 {
+		s.ExcludedNamespaces = override.ExcludedNamespaces
+	}
+	if override.LabelSelector != "" {
+		s.LabelSelector = override.LabelSelector
+	}
+	return s
+}
+
+// namespaces returns the namespaces to watch, metav1.NamespaceAll for every
+// namespace. Excluded namespaces are left out of the listed namespaces.
+func (s Scope) namespaces() []string {
+	if len(s.Namespaces) == 0 {
+		return []string{metav1.NamespaceAll}
+	}
+	var namespaces []string
+	for _, namespace := range s.Namespaces {
+		if !slice.ContainsString(s.ExcludedNamespaces, namespace) && !slice.ContainsString(namespaces, namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+// Contains returns whether objects in the namespace are in the scope.
+func (s Scope) Contains(namespace string) bool {
+	if slice.ContainsString(s.ExcludedNamespaces, namespace) {
+		return false
+	}
+	return len(s.Namespaces) == 0 || slice.ContainsString(s.Namespaces, namespace)
+}
+
+// NewScopedClient returns a client for the objects of a workload cluster in
+// the namespaces of the scope. Requests for objects in other namespaces, and
+// requests across all namespaces when the scope lists namespaces, fail with
+// traffic.ErrOutOfScope.
+func NewScopedClient(c client.Client, scope Scope) client.Client {
+	return &scopedClient{Client: c, scope: scope}
+}
+
+type scopedClient struct {
+	client.Client
+	scope Scope
+}
+
+func (c *scopedClient) check(namespace string) error {
+	if namespace == metav1.NamespaceAll && len(c.scope.Namespaces) == 0 {
+		return nil
+	}
+	if !c.scope.Contains(namespace) {
+		return fmt.Errorf("%w: %q", traffic.ErrOutOfScope, namespace)
+	}
+	return nil
+}
+
+func (c *scopedClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if err := c.check(key.Namespace); err != nil {
+		return err
+	}
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+func (c *scopedClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOptions := &client.ListOptions{}
+	listOptions.ApplyOptions(opts)
+	if err := c.check(listOptions.Namespace); err != nil {
+		return err
+	}
+	return c.Client.List(ctx, list, opts...)
+}
+
+func (c *scopedClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if err := c.check(obj.GetNamespace()); err != nil {
+		return err
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *scopedClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if err := c.check(obj.GetNamespace()); err != nil {
+		return err
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *scopedClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if err := c.check(obj.GetNamespace()); err != nil {
+		return err
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func (c *scopedClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if err := c.check(obj.GetNamespace()); err != nil {
+		return err
+	}
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func (c *scopedClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	deleteOptions := &client.DeleteAllOfOptions{}
+	deleteOptions.ApplyOptions(opts)
+	if err := c.check(deleteOptions.Namespace); err != nil {
+		return err
+	}
+	return c.Client.DeleteAllOf(ctx, obj, opts...)
+}
+
+// tweakListOptions restricts the objects listed and watched in a namespace to
+// those selected by the scope.
+func (s Scope) tweakListOptions(options *metav1.ListOptions) {
+	options.LabelSelector = s.LabelSelector
+	if len(s.Namespaces) > 0 || len(s.ExcludedNamespaces) == 0 {
+		return
+	}
+	var excluded []fields.Selector
+	for _, namespace := range s.ExcludedNamespaces {
+		excluded = append(excluded, fields.OneTermNotEqualSelector("metadata.namespace", namespace))
+	}
+	options.FieldSelector = fields.AndSelectors(excluded...).String()
+}
@@ -0,0 +1,138 @@
+package multiClusterWatch
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+func TestScope(t *testing.T) {
+	tests := []struct {
+		name                string
+		scope               Scope
+		expectNamespaces    []string
+		expectLabelSelector string
+		expectFieldSelector string
+	}{
+		{
+			name:             "every namespace",
+			expectNamespaces: []string{metav1.NamespaceAll},
+		},
+		{
+			name:             "allowed namespaces without the excluded ones",
+			scope:            Scope{Namespaces: []string{"a", "b", "a", "c"}, ExcludedNamespaces: []string{"b"}},
+			expectNamespaces: []string{"a", "c"},
+		},
+		{
+			name:                "excluded namespaces",
+			scope:               Scope{ExcludedNamespaces: []string{"kube-system", "openshift-ingress"}, LabelSelector: "team=a"},
+			expectNamespaces:    []string{metav1.NamespaceAll},
+			expectLabelSelector: "team=a",
+			expectFieldSelector: "metadata.namespace!=kube-system,metadata.namespace!=openshift-ingress",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if namespaces := tt.scope.namespaces(); !reflect.DeepEqual(namespaces, tt.expectNamespaces) {
+				t.Errorf("expected namespaces %v got %v", tt.expectNamespaces, namespaces)
+			}
+			options := &metav1.ListOptions{}
+			tt.scope.tweakListOptions(options)
+			if options.LabelSelector != tt.expectLabelSelector {
+				t.Errorf("expected label selector '%v' got '%v'", tt.expectLabelSelector, options.LabelSelector)
+			}
+			if options.FieldSelector != tt.expectFieldSelector {
+				t.Errorf("expected field selector '%v' got '%v'", tt.expectFieldSelector, options.FieldSelector)
+			}
+		})
+	}
+}
+
+func TestScopeFor(t *testing.T) {
+	global := Scope{Namespaces: []string{"apps"}, LabelSelector: "team=a"}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		WatchNamespacesAnnotation:         " shop, checkout ,",
+		WatchExcludedNamespacesAnnotation: "checkout",
+	}}}
+
+	scope, err := ScopeFor(secret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := Scope{Namespaces: []string{"shop", "checkout"}, ExcludedNamespaces: []string{"checkout"}, LabelSelector: "team=a"}
+	if overridden := global.Override(scope); !reflect.DeepEqual(overridden, expected) {
+		t.Errorf("expected scope %+v got %+v", expected, overridden)
+	}
+
+	secret.Annotations[WatchLabelSelectorAnnotation] = "team in (a"
+	if _, err := ScopeFor(secret); err == nil {
+		t.Errorf("expected an error for an invalid label selector")
+	}
+}
+
+func TestScopedClient(t *testing.T) {
+	secret := func(namespace string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "test-secret"}}
+	}
+	tests := []struct {
+		name      string
+		scope     Scope
+		namespace string
+		expectErr bool
+	}{
+		{
+			name:      "every namespace",
+			namespace: "team-a",
+		},
+		{
+			name:      "listed namespace",
+			scope:     Scope{Namespaces: []string{"team-a"}},
+			namespace: "team-a",
+		},
+		{
+			name:      "namespace that is not listed",
+			scope:     Scope{Namespaces: []string{"team-a"}},
+			namespace: "team-b",
+			expectErr: true,
+		},
+		{
+			name:      "excluded namespace",
+			scope:     Scope{ExcludedNamespaces: []string{"kube-system"}},
+			namespace: "kube-system",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewScopedClient(fake.NewClientBuilder().WithObjects(secret(tt.namespace)).Build(), tt.scope)
+			err := c.Get(context.TODO(), client.ObjectKeyFromObject(secret(tt.namespace)), &corev1.Secret{})
+			if tt.expectErr != errors.Is(err, traffic.ErrOutOfScope) {
+				t.Errorf("expected out of scope error %v got '%v'", tt.expectErr, err)
+			}
+			err = c.Delete(context.TODO(), secret(tt.namespace))
+			if tt.expectErr != errors.Is(err, traffic.ErrOutOfScope) {
+				t.Errorf("expected out of scope error %v got '%v'", tt.expectErr, err)
+			}
+		})
+	}
+
+	// listing across every namespace is only allowed when the scope lists no
+	// namespaces
+	c := NewScopedClient(fake.NewClientBuilder().Build(), Scope{Namespaces: []string{"team-a"}})
+	if err := c.List(context.TODO(), &corev1.SecretList{}); !errors.Is(err, traffic.ErrOutOfScope) {
+		t.Errorf("expected out of scope error got '%v'", err)
+	}
+	if err := c.List(context.TODO(), &corev1.SecretList{}, client.InNamespace("team-a")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
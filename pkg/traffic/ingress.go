@@ -1,17 +1,38 @@
 package traffic
 
 import (
+	"context"
 	"fmt"
 
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/utils/strings/slices"
 
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
 )
 
+func init() {
+	Register(Kind{
+		Name: "Ingress",
+		GVR:  networkingv1.SchemeGroupVersion.WithResource("ingresses"),
+		New: func(obj *unstructured.Unstructured) (Interface, error) {
+			ingress := &networkingv1.Ingress{}
+			if err := fromUnstructured(obj, ingress); err != nil {
+				return nil, err
+			}
+			return NewIngress(ingress), nil
+		},
+		WriteBack: func(ctx context.Context, c dynamic.ResourceInterface, before, after Interface) error {
+			return patch(ctx, c, before.(*Ingress).Ingress, after.(*Ingress).Ingress)
+		},
+	})
+}
+
 func NewIngress(i *networkingv1.Ingress) *Ingress {
 	return &Ingress{Ingress: i}
 }
@@ -35,37 +56,120 @@ func (a *Ingress) GetHosts() []string {
 	return hosts
 }
 
-func (a *Ingress) AddTLS(host string, secret *corev1.Secret) {
+func (a *Ingress) GetDNSTargets() []string {
+	var targets []string
+	for _, lb := range a.Status.LoadBalancer.Ingress {
+		if lb.IP != "" {
+			targets = append(targets, lb.IP)
+		} else if lb.Hostname != "" {
+			targets = append(targets, lb.Hostname)
+		}
+	}
+	return targets
+}
+
+func (a *Ingress) AddTLS(host string, secret *corev1.Secret) error {
 	for i, tls := range a.Spec.TLS {
 		if slice.ContainsString(tls.Hosts, host) {
 			a.Spec.TLS[i] = networkingv1.IngressTLS{
 				Hosts:      []string{host},
 				SecretName: secret.Name,
 			}
-			return
+			return nil
 		}
 	}
 	a.Spec.TLS = append(a.Spec.TLS, networkingv1.IngressTLS{
 		Hosts:      []string{host},
 		SecretName: secret.GetName(),
 	})
+	return nil
 }
 
-func (a *Ingress) RemoveTLS(hosts []string) {
-	for _, removeHost := range hosts {
-		for i, tls := range a.Spec.TLS {
-			tlsHosts := tls.Hosts
-			for j, host := range tls.Hosts {
-				if host == removeHost {
-					tlsHosts = append(hosts[:j], hosts[j+1:]...)
-				}
+// RemoveTLS removes the hosts from the TLS entries of the ingress, dropping
+// entries that have no hosts left.
+func (a *Ingress) RemoveTLS(hosts []string) error {
+	if len(hosts) == 0 {
+		return nil
+	}
+	var entries []networkingv1.IngressTLS
+	for _, tls := range a.Spec.TLS {
+		var remaining []string
+		for _, host := range tls.Hosts {
+			if !slice.ContainsString(hosts, host) {
+				remaining = append(remaining, host)
 			}
-			// if there are no hosts remaining remove the entry for TLS
-			if len(tlsHosts) == 0 {
-				a.Spec.TLS = append(a.Spec.TLS[:i], a.Spec.TLS[i+1:]...)
+		}
+		if len(remaining) == 0 {
+			continue
+		}
+		tls.Hosts = remaining
+		entries = append(entries, tls)
+	}
+	a.Spec.TLS = entries
+	return nil
+}
+
+// AddManagedHost assigns the host to the rules of the ingress that have no
+// host, marking the ingress with the pending annotation. If every rule
+// already has a host, a copy of every rule is added for the host instead, so
+// the host is routed the same way as the hosts already served.
+func (a *Ingress) AddManagedHost(host string) error {
+	pending := false
+	for _, rule := range a.Spec.Rules {
+		if rule.Host == host {
+			return nil
+		}
+		if rule.Host == "" {
+			pending = true
+		}
+	}
+	if pending {
+		metadata.AddAnnotation(a, PendingAnnotation, "true")
+		for i := range a.Spec.Rules {
+			if a.Spec.Rules[i].Host == "" {
+				a.Spec.Rules[i].Host = host
 			}
 		}
+		return nil
+	}
+
+	var rules []networkingv1.IngressRule
+	for _, rule := range a.Spec.Rules {
+		managed := rule.DeepCopy()
+		managed.Host = host
+		rules = append(rules, *managed)
+	}
+	if len(rules) == 0 {
+		// the ingress only has a default backend
+		rules = append(rules, networkingv1.IngressRule{Host: host})
+	}
+	a.Spec.Rules = append(a.Spec.Rules, rules...)
+	return nil
+}
+
+// RemoveManagedHost removes the host from the rules it was assigned to when
+// the ingress is marked with the pending annotation, or removes the
+// rules added for the host when there are none. Changes made to the rules
+// while the host was managed are kept.
+func (a *Ingress) RemoveManagedHost(host string) error {
+	if metadata.HasAnnotation(a, PendingAnnotation) {
+		for i := range a.Spec.Rules {
+			if a.Spec.Rules[i].Host == host {
+				a.Spec.Rules[i].Host = ""
+			}
+		}
+		metadata.RemoveAnnotation(a, PendingAnnotation)
+		return nil
+	}
+
+	var rules []networkingv1.IngressRule
+	for _, rule := range a.Spec.Rules {
+		if rule.Host != host {
+			rules = append(rules, rule)
+		}
 	}
+	a.Spec.Rules = rules
+	return nil
 }
 
 func (a *Ingress) GetSpec() interface{} {
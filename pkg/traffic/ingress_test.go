@@ -0,0 +1,107 @@
+package traffic
+
+import (
+	"reflect"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_ingressManagedHostPending(t *testing.T) {
+	rules := []networkingv1.IngressRule{
+		{
+			Host: "",
+			IngressRuleValue: networkingv1.IngressRuleValue{
+				HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{Path: "/"}},
+				},
+			},
+		},
+		{
+			Host: "app.team.com",
+		},
+	}
+	ingress := NewIngress(&networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-ingress"},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{*rules[0].DeepCopy(), *rules[1].DeepCopy()},
+		},
+	})
+
+	if err := ingress.AddManagedHost("test.example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ingress.Spec.Rules) != 2 {
+		t.Fatalf("expected rules to be rewritten in place, got: %v", ingress.Spec.Rules)
+	}
+	if ingress.Spec.Rules[0].Host != "test.example.com" || ingress.Spec.Rules[1].Host != "app.team.com" {
+		t.Fatalf("expected only the rule with no host to be assigned the host, got: %v", ingress.Spec.Rules)
+	}
+	if _, ok := ingress.GetAnnotations()[PendingAnnotation]; !ok {
+		t.Fatalf("expected the ingress to be marked pending")
+	}
+
+	// the user adds a path and a rule while the host is managed
+	ingress.Spec.Rules[0].HTTP.Paths = append(ingress.Spec.Rules[0].HTTP.Paths, networkingv1.HTTPIngressPath{Path: "/api"})
+	ingress.Spec.Rules = append(ingress.Spec.Rules, networkingv1.IngressRule{Host: "api.team.com"})
+	rules[0].HTTP.Paths = append(rules[0].HTTP.Paths, networkingv1.HTTPIngressPath{Path: "/api"})
+	rules = append(rules, networkingv1.IngressRule{Host: "api.team.com"})
+
+	if err := ingress.RemoveManagedHost("test.example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(ingress.Spec.Rules, rules) {
+		t.Fatalf("expected the rules '%v' with the changes of the user, got: %v", rules, ingress.Spec.Rules)
+	}
+	if _, ok := ingress.GetAnnotations()[PendingAnnotation]; ok {
+		t.Fatalf("expected the pending annotation to be removed")
+	}
+}
+
+func Test_ingressRemoveTLS(t *testing.T) {
+	tests := []struct {
+		name   string
+		tls    []networkingv1.IngressTLS
+		remove []string
+		expect []networkingv1.IngressTLS
+	}{
+		{
+			name:   "removes the entry of the host",
+			tls:    []networkingv1.IngressTLS{{Hosts: []string{"a.example.com"}, SecretName: "a"}, {Hosts: []string{"b.example.com"}, SecretName: "b"}},
+			remove: []string{"a.example.com"},
+			expect: []networkingv1.IngressTLS{{Hosts: []string{"b.example.com"}, SecretName: "b"}},
+		},
+		{
+			name:   "keeps the other hosts of a shared entry",
+			tls:    []networkingv1.IngressTLS{{Hosts: []string{"a.example.com", "b.example.com", "c.example.com"}, SecretName: "shared"}},
+			remove: []string{"b.example.com"},
+			expect: []networkingv1.IngressTLS{{Hosts: []string{"a.example.com", "c.example.com"}, SecretName: "shared"}},
+		},
+		{
+			name:   "removes consecutive entries",
+			tls:    []networkingv1.IngressTLS{{Hosts: []string{"a.example.com"}, SecretName: "a"}, {Hosts: []string{"b.example.com"}, SecretName: "b"}},
+			remove: []string{"a.example.com", "b.example.com"},
+			expect: nil,
+		},
+		{
+			name:   "keeps every entry without hosts to remove",
+			tls:    []networkingv1.IngressTLS{{SecretName: "default"}, {Hosts: []string{"a.example.com"}, SecretName: "a"}},
+			expect: []networkingv1.IngressTLS{{SecretName: "default"}, {Hosts: []string{"a.example.com"}, SecretName: "a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ingress := NewIngress(&networkingv1.Ingress{
+				Spec: networkingv1.IngressSpec{TLS: tt.tls},
+			})
+			if err := ingress.RemoveTLS(tt.remove); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(ingress.Spec.TLS, tt.expect) {
+				t.Errorf("expected TLS '%v' got '%v'", tt.expect, ingress.Spec.TLS)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package traffic
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/client-go/dynamic"
+)
+
+// FieldManager identifies the controller as the manager of the fields it
+// writes on the workload clusters
+const FieldManager = "multi-cluster-traffic-controller"
+
+// Kind describes a traffic type that is watched on the workload clusters.
+type Kind struct {
+	// Name of the kind, matching the value returned by Interface.GetKind
+	Name string
+	// GVR is the resource watched on the workload clusters
+	GVR schema.GroupVersionResource
+	// New wraps an object read from a workload cluster in its traffic accessor
+	New func(obj *unstructured.Unstructured) (Interface, error)
+	// Filter optionally restricts the objects that are handled, all objects
+	// are handled when nil
+	Filter func(obj Interface) bool
+	// WriteBack persists the changes made between before and after to the
+	// workload cluster. A conflict error is returned when the object changed
+	// since before was read.
+	WriteBack func(ctx context.Context, c dynamic.ResourceInterface, before, after Interface) error
+}
+
+var (
+	registryLock sync.RWMutex
+	registry     = map[string]Kind{}
+)
+
+// Register adds a traffic kind to the registry. Kinds are expected to
+// register themselves from an init function, registering the same kind twice
+// panics.
+func Register(kind Kind) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if _, ok := registry[kind.Name]; ok {
+		panic(fmt.Sprintf("traffic kind %s is already registered", kind.Name))
+	}
+	registry[kind.Name] = kind
+}
+
+// Kinds returns all registered traffic kinds sorted by name.
+func Kinds() []Kind {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	kinds := make([]Kind, 0, len(registry))
+	for _, kind := range registry {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool {
+		return kinds[i].Name < kinds[j].Name
+	})
+	return kinds
+}
+
+// fromUnstructured converts an object read through the dynamic client into
+// its typed form.
+func fromUnstructured(u *unstructured.Unstructured, obj runtime.Object) error {
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), obj)
+}
+
+// patch writes the changes between before and after back through the dynamic
+// client as a JSON merge patch, so fields changed by other controllers are left
+// untouched. The resource version of before is included in the patch which
+// makes the API server reject it with a conflict if the object has changed in
+// the meantime.
+func patch(ctx context.Context, c dynamic.ResourceInterface, before, after metav1.Object) error {
+	beforeJSON, err := json.Marshal(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := json.Marshal(after)
+	if err != nil {
+		return err
+	}
+	mergePatch, err := jsonpatch.CreateMergePatch(beforeJSON, afterJSON)
+	if err != nil {
+		return err
+	}
+
+	patchMap := map[string]interface{}{}
+	if err := json.Unmarshal(mergePatch, &patchMap); err != nil {
+		return err
+	}
+	if len(patchMap) == 0 {
+		return nil
+	}
+	if err := unstructured.SetNestedField(patchMap, before.GetResourceVersion(), "metadata", "resourceVersion"); err != nil {
+		return err
+	}
+	mergePatch, err = json.Marshal(patchMap)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Patch(ctx, before.GetName(), types.MergePatchType, mergePatch, metav1.PatchOptions{FieldManager: FieldManager})
+	return err
+}
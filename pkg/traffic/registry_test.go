@@ -0,0 +1,117 @@
+package traffic
+
+import (
+	"context"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func Test_kinds(t *testing.T) {
+	kinds := Kinds()
+	expected := []string{"Ingress", "Route", "Service"}
+	if len(kinds) != len(expected) {
+		t.Fatalf("expected %v kinds, got: %v", len(expected), len(kinds))
+	}
+	for i, kind := range kinds {
+		if kind.Name != expected[i] {
+			t.Errorf("expected kind '%v' got '%v'", expected[i], kind.Name)
+		}
+		if kind.New == nil || kind.WriteBack == nil {
+			t.Errorf("expected kind '%v' to have a constructor and write back function", kind.Name)
+		}
+	}
+}
+
+func Test_kindNew(t *testing.T) {
+	for _, kind := range Kinds() {
+		t.Run(kind.Name, func(t *testing.T) {
+			u := &unstructured.Unstructured{}
+			u.SetAPIVersion(kind.GVR.GroupVersion().String())
+			u.SetKind(kind.Name)
+			u.SetNamespace("test-namespace")
+			u.SetName("test-object")
+
+			obj, err := kind.New(u)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if obj.GetKind() != kind.Name {
+				t.Errorf("expected kind '%v' got '%v'", kind.Name, obj.GetKind())
+			}
+			if obj.GetCacheKey() != "test-namespace/test-object" {
+				t.Errorf("expected cache key 'test-namespace/test-object' got '%v'", obj.GetCacheKey())
+			}
+		})
+	}
+}
+
+func Test_registerDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected registering a duplicate kind to panic")
+		}
+	}()
+	Register(Kind{Name: "Ingress"})
+}
+
+func Test_patch(t *testing.T) {
+	before := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test-ingress",
+			Namespace:       "test-namespace",
+			ResourceVersion: "1",
+			Labels:          map[string]string{"owned-by-someone-else": "value"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(ingress *networkingv1.Ingress)
+		expect string
+	}{
+		{
+			name:   "no changes sends no patch",
+			mutate: func(ingress *networkingv1.Ingress) {},
+			expect: "",
+		},
+		{
+			name: "only changed fields are sent with the resource version",
+			mutate: func(ingress *networkingv1.Ingress) {
+				ingress.Labels["test-key"] = "test-value"
+			},
+			expect: `{"metadata":{"labels":{"test-key":"test-value"},"resourceVersion":"1"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleDynamicClient(runtime.NewScheme())
+			var got string
+			client.PrependReactor("patch", "ingresses", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				patchAction := action.(k8stesting.PatchAction)
+				if patchAction.GetPatchType() != types.MergePatchType {
+					t.Errorf("expected patch type '%v' got '%v'", types.MergePatchType, patchAction.GetPatchType())
+				}
+				got = string(patchAction.GetPatch())
+				return true, nil, nil
+			})
+
+			after := before.DeepCopy()
+			tt.mutate(after)
+			resource := client.Resource(networkingv1.SchemeGroupVersion.WithResource("ingresses")).Namespace(before.Namespace)
+			if err := patch(context.TODO(), resource, before, after); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expect {
+				t.Errorf("expected patch '%v' got '%v'", tt.expect, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package traffic
+
+import (
+	"context"
+	"fmt"
+
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
+)
+
+const caCertKey = "ca.crt"
+
+func init() {
+	Register(Kind{
+		Name: "Route",
+		GVR:  routev1.GroupVersion.WithResource("routes"),
+		New: func(obj *unstructured.Unstructured) (Interface, error) {
+			route := &routev1.Route{}
+			if err := fromUnstructured(obj, route); err != nil {
+				return nil, err
+			}
+			return NewRoute(route), nil
+		},
+		WriteBack: func(ctx context.Context, c dynamic.ResourceInterface, before, after Interface) error {
+			return patch(ctx, c, before.(*Route).Route, after.(*Route).Route)
+		},
+	})
+}
+
+func NewRoute(r *routev1.Route) *Route {
+	return &Route{Route: r}
+}
+
+type Route struct {
+	*routev1.Route
+}
+
+func (a *Route) GetKind() string {
+	return "Route"
+}
+
+func (a *Route) GetHosts() []string {
+	return []string{a.Spec.Host}
+}
+
+// GetDNSTargets returns the canonical hostnames of the routers that admitted
+// the route
+func (a *Route) GetDNSTargets() []string {
+	var targets []string
+	for _, ingress := range a.Status.Ingress {
+		if ingress.RouterCanonicalHostname != "" && !slice.ContainsString(targets, ingress.RouterCanonicalHostname) {
+			targets = append(targets, ingress.RouterCanonicalHostname)
+		}
+	}
+	return targets
+}
+
+// AddTLS inlines the certificate material from the secret into the TLS block
+// of the route. Routes do not reference secrets so the route has to be updated
+// again whenever the secret changes.
+func (a *Route) AddTLS(host string, secret *corev1.Secret) error {
+	if a.Spec.Host != host {
+		return nil
+	}
+	if a.Spec.TLS == nil {
+		a.Spec.TLS = &routev1.TLSConfig{
+			Termination: routev1.TLSTerminationEdge,
+		}
+	}
+	a.Spec.TLS.Certificate = string(secret.Data[corev1.TLSCertKey])
+	a.Spec.TLS.Key = string(secret.Data[corev1.TLSPrivateKeyKey])
+	a.Spec.TLS.CACertificate = string(secret.Data[caCertKey])
+	return nil
+}
+
+// RemoveTLS removes the inlined certificate material from the route, leaving
+// the termination settings in place so the router falls back to its default
+// certificate.
+func (a *Route) RemoveTLS(hosts []string) error {
+	if a.Spec.TLS == nil || !slice.ContainsString(hosts, a.Spec.Host) {
+		return nil
+	}
+	a.Spec.TLS.Certificate = ""
+	a.Spec.TLS.Key = ""
+	a.Spec.TLS.CACertificate = ""
+	return nil
+}
+
+// AddManagedHost sets the host of the route. Routes serve a single host so
+// ErrHostConflict is returned if the route already has a different one.
+func (a *Route) AddManagedHost(host string) error {
+	if a.Spec.Host != "" && a.Spec.Host != host {
+		return ErrHostConflict
+	}
+	a.Spec.Host = host
+	return nil
+}
+
+func (a *Route) RemoveManagedHost(host string) error {
+	if a.Spec.Host == host {
+		a.Spec.Host = ""
+	}
+	return nil
+}
+
+func (a *Route) GetSpec() interface{} {
+	return a.Spec
+}
+
+func (a *Route) GetNamespaceName() types.NamespacedName {
+	return types.NamespacedName{
+		Namespace: a.Namespace,
+		Name:      a.Name,
+	}
+}
+
+func (a *Route) GetCacheKey() string {
+	key, _ := cache.MetaNamespaceKeyFunc(a)
+	return key
+}
+
+func (a *Route) String() string {
+	return fmt.Sprintf("kind: %v, namespace/name: %v", a.GetKind(), a.GetNamespaceName())
+}
@@ -0,0 +1,98 @@
+package traffic
+
+import (
+	"reflect"
+	"testing"
+
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_routeAddTLS(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-secret"},
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       []byte("cert"),
+			corev1.TLSPrivateKeyKey: []byte("key"),
+			caCertKey:               []byte("ca"),
+		},
+	}
+
+	tests := []struct {
+		name   string
+		route  *routev1.Route
+		host   string
+		expect *routev1.TLSConfig
+	}{
+		{
+			name:  "adds edge TLS when route has no TLS",
+			route: &routev1.Route{Spec: routev1.RouteSpec{Host: "test.example.com"}},
+			host:  "test.example.com",
+			expect: &routev1.TLSConfig{
+				Termination:   routev1.TLSTerminationEdge,
+				Certificate:   "cert",
+				Key:           "key",
+				CACertificate: "ca",
+			},
+		},
+		{
+			name: "keeps existing termination settings",
+			route: &routev1.Route{Spec: routev1.RouteSpec{
+				Host: "test.example.com",
+				TLS: &routev1.TLSConfig{
+					Termination:                   routev1.TLSTerminationReencrypt,
+					InsecureEdgeTerminationPolicy: routev1.InsecureEdgeTerminationPolicyRedirect,
+				},
+			}},
+			host: "test.example.com",
+			expect: &routev1.TLSConfig{
+				Termination:                   routev1.TLSTerminationReencrypt,
+				InsecureEdgeTerminationPolicy: routev1.InsecureEdgeTerminationPolicyRedirect,
+				Certificate:                   "cert",
+				Key:                           "key",
+				CACertificate:                 "ca",
+			},
+		},
+		{
+			name:   "ignores hosts not served by the route",
+			route:  &routev1.Route{Spec: routev1.RouteSpec{Host: "test.example.com"}},
+			host:   "other.example.com",
+			expect: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NewRoute(tt.route).AddTLS(tt.host, secret); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(tt.route.Spec.TLS, tt.expect) {
+				t.Errorf("expected TLS '%+v' got '%+v'", tt.expect, tt.route.Spec.TLS)
+			}
+		})
+	}
+}
+
+func Test_routeRemoveTLS(t *testing.T) {
+	route := &routev1.Route{Spec: routev1.RouteSpec{
+		Host: "test.example.com",
+		TLS: &routev1.TLSConfig{
+			Termination:   routev1.TLSTerminationEdge,
+			Certificate:   "cert",
+			Key:           "key",
+			CACertificate: "ca",
+		},
+	}}
+
+	_ = NewRoute(route).RemoveTLS([]string{"other.example.com"})
+	if route.Spec.TLS.Certificate != "cert" {
+		t.Errorf("expected certificate to be kept for unrelated host")
+	}
+
+	_ = NewRoute(route).RemoveTLS([]string{"test.example.com"})
+	expect := &routev1.TLSConfig{Termination: routev1.TLSTerminationEdge}
+	if !reflect.DeepEqual(route.Spec.TLS, expect) {
+		t.Errorf("expected TLS '%+v' got '%+v'", expect, route.Spec.TLS)
+	}
+}
@@ -0,0 +1,130 @@
+package traffic
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/strings/slices"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
+)
+
+// ServiceHostnameAnnotation holds a comma separated list of the hosts a
+// LoadBalancer service should be reachable on.
+const ServiceHostnameAnnotation = "kuadrant.io/hostname"
+
+func init() {
+	Register(Kind{
+		Name: "Service",
+		GVR:  corev1.SchemeGroupVersion.WithResource("services"),
+		New: func(obj *unstructured.Unstructured) (Interface, error) {
+			service := &corev1.Service{}
+			if err := fromUnstructured(obj, service); err != nil {
+				return nil, err
+			}
+			return NewService(service), nil
+		},
+		// only load balancer services are reachable from outside the cluster
+		Filter: func(obj Interface) bool {
+			return obj.(*Service).Spec.Type == corev1.ServiceTypeLoadBalancer
+		},
+		WriteBack: func(ctx context.Context, c dynamic.ResourceInterface, before, after Interface) error {
+			return patch(ctx, c, before.(*Service).Service, after.(*Service).Service)
+		},
+	})
+}
+
+func NewService(s *corev1.Service) *Service {
+	return &Service{Service: s}
+}
+
+type Service struct {
+	*corev1.Service
+}
+
+func (a *Service) GetKind() string {
+	return "Service"
+}
+
+func (a *Service) GetHosts() []string {
+	var hosts []string
+	for _, host := range strings.Split(metadata.GetAnnotation(a, ServiceHostnameAnnotation), ",") {
+		host = strings.TrimSpace(host)
+		if host != "" && !slices.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+func (a *Service) GetDNSTargets() []string {
+	var targets []string
+	for _, lb := range a.Status.LoadBalancer.Ingress {
+		if lb.IP != "" {
+			targets = append(targets, lb.IP)
+		} else if lb.Hostname != "" {
+			targets = append(targets, lb.Hostname)
+		}
+	}
+	return targets
+}
+
+// AddTLS is not supported, services are exposed at layer 4 and TLS is left to
+// the workload.
+func (a *Service) AddTLS(_ string, _ *corev1.Secret) error {
+	return ErrTLSNotSupported
+}
+
+func (a *Service) RemoveTLS(_ []string) error {
+	return ErrTLSNotSupported
+}
+
+func (a *Service) AddManagedHost(host string) error {
+	hosts := a.GetHosts()
+	if slices.Contains(hosts, host) {
+		return nil
+	}
+	metadata.AddAnnotation(a, ServiceHostnameAnnotation, strings.Join(append(hosts, host), ","))
+	return nil
+}
+
+func (a *Service) RemoveManagedHost(host string) error {
+	hosts := a.GetHosts()
+	if !slices.Contains(hosts, host) {
+		return nil
+	}
+	hosts = slice.RemoveString(hosts, host)
+	if len(hosts) == 0 {
+		metadata.RemoveAnnotation(a, ServiceHostnameAnnotation)
+		return nil
+	}
+	metadata.AddAnnotation(a, ServiceHostnameAnnotation, strings.Join(hosts, ","))
+	return nil
+}
+
+func (a *Service) GetSpec() interface{} {
+	return a.Spec
+}
+
+func (a *Service) GetNamespaceName() types.NamespacedName {
+	return types.NamespacedName{
+		Namespace: a.Namespace,
+		Name:      a.Name,
+	}
+}
+
+func (a *Service) GetCacheKey() string {
+	key, _ := cache.MetaNamespaceKeyFunc(a)
+	return key
+}
+
+func (a *Service) String() string {
+	return fmt.Sprintf("kind: %v, namespace/name: %v", a.GetKind(), a.GetNamespaceName())
+}
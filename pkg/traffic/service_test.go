@@ -0,0 +1,62 @@
+package traffic
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_serviceGetHosts(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expect      []string
+	}{
+		{
+			name:        "no hostname annotation",
+			annotations: nil,
+			expect:      nil,
+		},
+		{
+			name: "single host",
+			annotations: map[string]string{
+				ServiceHostnameAnnotation: "test.example.com",
+			},
+			expect: []string{"test.example.com"},
+		},
+		{
+			name: "multiple hosts with whitespace and duplicates",
+			annotations: map[string]string{
+				ServiceHostnameAnnotation: "test.example.com, other.example.com,,test.example.com",
+			},
+			expect: []string{"test.example.com", "other.example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-service",
+					Annotations: tt.annotations,
+				},
+			})
+			if got := service.GetHosts(); !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("expected hosts '%v' got '%v'", tt.expect, got)
+			}
+		})
+	}
+}
+
+func Test_serviceTLSNotSupported(t *testing.T) {
+	service := NewService(&corev1.Service{})
+	if err := service.AddTLS("test.example.com", &corev1.Secret{}); !errors.Is(err, ErrTLSNotSupported) {
+		t.Errorf("expected '%v' got '%v'", ErrTLSNotSupported, err)
+	}
+	if err := service.RemoveTLS([]string{"test.example.com"}); !errors.Is(err, ErrTLSNotSupported) {
+		t.Errorf("expected '%v' got '%v'", ErrTLSNotSupported, err)
+	}
+}
@@ -2,14 +2,44 @@ package traffic
 
 import (
 	"context"
+	"errors"
 
 	corev1 "k8s.io/api/core/v1"
-	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 )
 
+const (
+	// ManagedLabel opts a traffic object in to host management when set to
+	// "true", either as a label or an annotation
+	ManagedLabel = "kuadrant.io/managed"
+	// ManagedHostAnnotation records the host generated for a managed traffic
+	// object
+	ManagedHostAnnotation = "kuadrant.io/managed-host"
+	// PendingAnnotation marks an ingress whose rules with no host were
+	// rewritten to the managed host, so the host is cleared from the rules
+	// rather than the rules removed
+	PendingAnnotation = "kuadrant.io/pending"
+	// VerificationAnnotation lists the TXT records, and the tokens expected
+	// in them, that verify the custom hosts of a managed traffic object
+	VerificationAnnotation = "kuadrant.io/domain-verification"
+	// TLSHostsAnnotation lists the hosts of a traffic object that TLS was
+	// added for by the controller, comma separated
+	TLSHostsAnnotation = "kuadrant.io/tls-hosts"
+)
+
+var (
+	// ErrTLSNotSupported is returned by traffic objects that cannot terminate TLS
+	ErrTLSNotSupported = errors.New("TLS is not supported for this traffic type")
+	// ErrOutOfScope is returned by workload cluster clients for objects
+	// outside the namespaces the controller watches on the cluster
+	ErrOutOfScope = errors.New("the namespace is outside the watch scope of the cluster")
+	// ErrHostConflict is returned by traffic objects that serve a single host
+	// when asked to serve another one
+	ErrHostConflict = errors.New("traffic object already serves a different host")
+)
+
 type CreateOrUpdateTraffic func(ctx context.Context, i Interface) error
 type DeleteTraffic func(ctx context.Context, i Interface) error
 
@@ -18,13 +48,24 @@ type Interface interface {
 	metav1.Object
 	GetKind() string
 	GetHosts() []string
+	// GetDNSTargets returns the addresses, IPs or hostnames, the hosts
+	// should resolve to
+	GetDNSTargets() []string
 	GetCacheKey() string
 	GetNamespaceName() types.NamespacedName
-	AddTLS(host string, secret *corev1.Secret)
-	RemoveTLS(host []string)
+	AddTLS(host string, secret *corev1.Secret) error
+	RemoveTLS(host []string) error
+	// AddManagedHost makes the object serve the host in addition to the
+	// hosts it already serves
+	AddManagedHost(host string) error
+	// RemoveManagedHost stops the object serving a host previously added by
+	// AddManagedHost
+	RemoveManagedHost(host string) error
 	GetSpec() interface{}
 }
 
-type Pending struct {
-	Rules []networkingv1.IngressRule `json:"rules"`
+// IsManaged returns whether the object opted in to host management through
+// the managed label or annotation.
+func IsManaged(obj metav1.Object) bool {
+	return obj.GetLabels()[ManagedLabel] == "true" || obj.GetAnnotations()[ManagedLabel] == "true"
 }